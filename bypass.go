@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBypassLimit = errors.New("circuit bypass limit exceeded")
+)
+
+type bypassKey struct{}
+
+// WithBypass - помечает контекст как диагностический запрос, который пройдет через предохранитель
+// в статусе opened. В остальных статусах такой запрос выполняется как обычный.
+// Должен вызываться только доверенным кодом
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypass - помечен ли контекст через WithBypass
+func IsBypass(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// BypassStats - исходы диагностических запросов. Они не влияют на статус предохранителя
+type BypassStats struct {
+	Calls    int64 `json:"calls"`
+	Failures int64 `json:"failures"`
+	// Limited - запросы, отклоненные из-за лимита
+	Limited int64 `json:"limited"`
+}
+
+type bypass struct {
+	mx sync.Mutex
+	// limit - сколько диагностических запросов разрешено за interval
+	limit    int
+	interval time.Duration
+	// windowStart, windowCalls - текущее окно лимита
+	windowStart time.Time
+	windowCalls int
+	stats       BypassStats
+}
+
+// SetBypassLimit - разрешает не больше limit диагностических запросов (см. WithBypass) за interval.
+// Пока лимит не задан, пометка WithBypass игнорируется
+func (cb *CircuitBreaker[TRequest, TResponse]) SetBypassLimit(limit int, interval time.Duration) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	cb.bypass = &bypass{
+		limit:    limit,
+		interval: interval,
+	}
+}
+
+// BypassStats - статистика диагностических запросов
+func (cb *CircuitBreaker[TRequest, TResponse]) BypassStats() BypassStats {
+	cb.mx.Lock()
+	b := cb.bypass
+	cb.mx.Unlock()
+
+	if b == nil {
+		return BypassStats{}
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return b.stats
+}
+
+// executeBypass - выполняет диагностический запрос. Исход учитывается только в BypassStats,
+// чтобы диагностика не могла случайно закрыть предохранитель
+func (cb *CircuitBreaker[TRequest, TResponse]) executeBypass(ctx context.Context, b *bypass, params TRequest, f func(context.Context, TRequest) (TResponse, error)) (TResponse, error) {
+	if !b.allow() {
+		return *new(TResponse), ErrBypassLimit
+	}
+
+	result, err := call(ctx, cb.timeout, cb.abandoned, params, f)
+
+	b.mx.Lock()
+	b.stats.Calls++
+	if err != nil {
+		b.stats.Failures++
+	}
+	b.mx.Unlock()
+
+	if err != nil {
+		return *new(TResponse), err
+	}
+
+	return result, nil
+}
+
+func (b *bypass) allow() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	now := time.Now()
+	if now.Sub(b.windowStart) >= b.interval {
+		b.windowStart = now
+		b.windowCalls = 0
+	}
+
+	if b.windowCalls >= b.limit {
+		b.stats.Limited++
+		return false
+	}
+
+	b.windowCalls++
+
+	return true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Execute_Bypass(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+
+	ctx := context.Background()
+	diagnostic := WithBypass(ctx)
+
+	failing := func(context.Context, time.Duration) (string, error) {
+		return "", errors.New("fail")
+	}
+
+	if _, err := cb.Execute(ctx, 0, failing); err == nil {
+		t.Fatalf("Wanted error, but got nil")
+	}
+
+	// без лимита пометка игнорируется
+	if _, err := cb.Execute(diagnostic, 0, F); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	cb.SetBypassLimit(2, time.Minute)
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		f       func(context.Context, time.Duration) (string, error)
+		wantErr error
+	}{
+		{
+			name:    "Fail_Status_Open",
+			ctx:     ctx,
+			f:       F,
+			wantErr: ErrCircuitOpened,
+		},
+		{
+			name: "Success_Bypass",
+			ctx:  diagnostic,
+			f:    F,
+		},
+		{
+			name:    "Fail_Bypass",
+			ctx:     diagnostic,
+			f:       failing,
+			wantErr: errors.New("fail"),
+		},
+		{
+			name:    "Fail_Bypass_Limit",
+			ctx:     diagnostic,
+			f:       F,
+			wantErr: ErrBypassLimit,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Running test case: %s", testCase.name)
+
+		_, err := cb.Execute(testCase.ctx, 0, testCase.f)
+		if testCase.wantErr == nil && err != nil {
+			t.Errorf("Got error: %s", err.Error())
+			continue
+		}
+
+		if testCase.wantErr != nil && (err == nil || err.Error() != testCase.wantErr.Error()) {
+			t.Errorf("Wanted error %s, but got %v", testCase.wantErr, err)
+		}
+	}
+
+	if stats := cb.BypassStats(); stats != (BypassStats{Calls: 2, Failures: 1, Limited: 1}) {
+		t.Errorf("Got unexpected bypass stats: %+v", stats)
+	}
+
+	// диагностический запрос на месте обычного не закрывает предохранитель
+	if cb.Status() != StatusOpen {
+		t.Errorf("Wanted status %s, but got %s", StatusOpen, cb.Status())
+	}
+}
+
+func TestCircuitBreaker_Execute_Bypass_HalfOpen(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+	cb.SetBypassLimit(0, time.Minute)
+	cb.setStatus(StatusHalfOpen)
+
+	// в статусе halfOpen предохранитель пропускает запрос сам, лимит обхода не нужен
+	if _, err := cb.Execute(WithBypass(context.Background()), 0, F); err != nil {
+		t.Errorf("Got error: %s", err.Error())
+	}
+
+	if stats := cb.BypassStats(); stats != (BypassStats{}) {
+		t.Errorf("Wanted call not to be counted as bypass, but got %+v", stats)
+	}
+
+	if cb.Status() != StatusClosed {
+		t.Errorf("Wanted status %s, but got %s", StatusClosed, cb.Status())
+	}
+}
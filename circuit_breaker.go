@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,6 +20,23 @@ const (
 	StatusHalfOpen
 )
 
+func (s Status) String() string {
+	switch s {
+	case StatusClosed:
+		return "closed"
+	case StatusOpen:
+		return "open"
+	case StatusHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 type CircuitBreaker[TRequest, TResponse any] struct {
 	mx *sync.Mutex
 	// timeout - лимит обработки запроса
@@ -35,6 +53,34 @@ type CircuitBreaker[TRequest, TResponse any] struct {
 	responsesThreshold int64
 	// responses - хранит в себе результаты запросов (fail - false, success - true)
 	responses []bool
+	// history - история метрик предохранителя (nil - история не ведется)
+	history *History
+	// reliability - сбор данных для отчетов о надежности (nil - не собираются)
+	reliability *ReliabilityRecorder
+	// fallback - источник ответов для запросов, отклоненных в статусе opened (nil - ответ не подменяется)
+	fallback Fallback[TRequest, TResponse]
+	// trips - сколько раз предохранитель переходил в статус opened
+	trips int64
+	// bypass - лимит и статистика диагностических запросов в обход предохранителя (nil - обход выключен)
+	bypass *bypass
+	// override - ручное переопределение статуса (nil - статус меняется автоматически)
+	override *override
+	// onOverrideExpired - вызывается, когда ручное переопределение истекло
+	onOverrideExpired func(Override)
+	// warning - настройки и состояние предупреждения перед срабатыванием (nil - предупреждения выключены)
+	warning *warning
+	// abandoned - сколько вызовов f все еще выполняются после истечения timeout
+	abandoned *atomic.Int64
+	// listeners - подписчики на смену статуса
+	listeners map[int64]func(from, to Status)
+	// nextListener - идентификатор следующего подписчика
+	nextListener int64
+}
+
+// Fallback - источник ответа для запроса, который предохранитель отклонил
+type Fallback[TRequest, TResponse any] interface {
+	// Fallback - ответ для params. false - подходящего ответа нет
+	Fallback(ctx context.Context, params TRequest) (TResponse, bool)
 }
 
 func NewCB[TRequest, TResponse any](timeout, recoverTimeout time.Duration, errorThreshold float64, halfOpenLimit int64, responsesThreshold int64) *CircuitBreaker[TRequest, TResponse] {
@@ -47,18 +93,122 @@ func NewCB[TRequest, TResponse any](timeout, recoverTimeout time.Duration, error
 		halfOpenLimit:      halfOpenLimit,
 		responsesThreshold: responsesThreshold,
 		responses:          make([]bool, 0, responsesThreshold+1),
+		abandoned:          &atomic.Int64{},
 	}
 }
 
+// SetHistory - включает запись истории метрик предохранителя в h
+func (cb *CircuitBreaker[TRequest, TResponse]) SetHistory(h *History) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	cb.history = h
+}
+
+// SetReliability - включает сбор данных для отчетов о надежности в r
+func (cb *CircuitBreaker[TRequest, TResponse]) SetReliability(r *ReliabilityRecorder) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	r.attach(cb.status)
+	cb.reliability = r
+}
+
+// SetFallback - запросы, отклоненные в статусе opened, будут обслуживаться из fallback
+func (cb *CircuitBreaker[TRequest, TResponse]) SetFallback(fallback Fallback[TRequest, TResponse]) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	cb.fallback = fallback
+}
+
+// Trips - сколько раз предохранитель переходил в статус opened с момента создания.
+// Переходы по ручному переопределению (ForceOpen) не учитываются
+func (cb *CircuitBreaker[TRequest, TResponse]) Trips() int64 {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	return cb.trips
+}
+
+// Abandoned - сколько вызовов f не завершились к истечению timeout и все еще выполняются
+func (cb *CircuitBreaker[TRequest, TResponse]) Abandoned() int64 {
+	return cb.abandoned.Load()
+}
+
+// Status - текущий статус предохранителя
+func (cb *CircuitBreaker[TRequest, TResponse]) Status() Status {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	return cb.status
+}
+
 func (cb *CircuitBreaker[TRequest, TResponse]) Execute(ctx context.Context, params TRequest, f func(context.Context, TRequest) (TResponse, error)) (TResponse, error) {
+	result, _, err := cb.execute(ctx, params, f)
+
+	return result, err
+}
+
+// execute - Execute, который дополнительно сообщает, что запрос отклонен в статусе opened,
+// в том числе если ответ на него подменил fallback
+func (cb *CircuitBreaker[TRequest, TResponse]) execute(ctx context.Context, params TRequest, f func(context.Context, TRequest) (TResponse, error)) (TResponse, bool, error) {
 	cb.mx.Lock()
+	// в статусе halfOpen запрос и так пропускается, поэтому обход нужен только в статусе opened
+	if cb.bypass != nil && cb.status == StatusOpen && IsBypass(ctx) {
+		bypass := cb.bypass
+		cb.mx.Unlock()
+
+		result, err := cb.executeBypass(ctx, bypass, params, f)
+
+		return result, false, err
+	}
+
 	if cb.status == StatusOpen {
+		fallback := cb.fallback
 		cb.mx.Unlock()
-		return *new(TResponse), ErrCircuitOpened
+
+		if fallback != nil {
+			if result, ok := fallback.Fallback(ctx, params); ok {
+				cb.recordRejection(true)
+
+				return result, true, nil
+			}
+		}
+
+		cb.recordRejection(false)
+
+		return *new(TResponse), true, ErrCircuitOpened
 	}
 	cb.mx.Unlock()
 
-	ctx, cancel := context.WithTimeout(ctx, cb.timeout)
+	start := time.Now()
+
+	result, err := call(ctx, cb.timeout, cb.abandoned, params, f)
+	if err != nil {
+		cb.handleResponse(false)
+		cb.recordCall(start, false)
+
+		return *new(TResponse), false, err
+	}
+
+	cb.handleResponse(true)
+	cb.recordCall(start, true)
+
+	return result, false, nil
+}
+
+const (
+	callRunning int32 = iota
+	callDone
+	callAbandoned
+)
+
+// call - выполняет f с лимитом timeout. Если лимит истек раньше, чем f вернула результат,
+// call сразу возвращает ошибку контекста, не дожидаясь f. Пока брошенная f выполняется,
+// она учитывается в abandoned (может быть nil)
+func call[TRequest, TResponse any](ctx context.Context, timeout time.Duration, abandoned *atomic.Int64, params TRequest, f func(context.Context, TRequest) (TResponse, error)) (TResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	type response struct {
@@ -68,11 +218,18 @@ func (cb *CircuitBreaker[TRequest, TResponse]) Execute(ctx context.Context, para
 
 	ch := make(chan response)
 
+	// state - callRunning, пока f выполняется, затем callDone или callAbandoned, если call перестала ее ждать
+	var state atomic.Int32
+
 	go func() {
 		defer close(ch)
 
 		result, err := f(ctx, params)
 
+		if !state.CompareAndSwap(callRunning, callDone) && abandoned != nil {
+			abandoned.Add(-1)
+		}
+
 		select {
 		case <-ctx.Done():
 		case ch <- response{result: result, err: err}:
@@ -82,19 +239,42 @@ func (cb *CircuitBreaker[TRequest, TResponse]) Execute(ctx context.Context, para
 
 	select {
 	case <-ctx.Done():
-		cb.handleResponse(false)
+		if state.CompareAndSwap(callRunning, callAbandoned) && abandoned != nil {
+			abandoned.Add(1)
+		}
 
 		return *new(TResponse), ctx.Err()
 	case result := <-ch:
-		if result.err != nil {
-			cb.handleResponse(false)
+		return result.result, result.err
+	}
+}
 
-			return *new(TResponse), result.err
-		}
+func (cb *CircuitBreaker[TRequest, TResponse]) recordCall(start time.Time, success bool) {
+	cb.mx.Lock()
+	status, history, reliability := cb.status, cb.history, cb.reliability
+	warning := cb.warning != nil && cb.warning.active
+	cb.mx.Unlock()
 
-		cb.handleResponse(true)
+	if history != nil {
+		history.addCall(status, warning, time.Since(start), success)
+	}
 
-		return result.result, nil
+	if reliability != nil {
+		reliability.addCall(success)
+	}
+}
+
+func (cb *CircuitBreaker[TRequest, TResponse]) recordRejection(fallback bool) {
+	cb.mx.Lock()
+	status, history, reliability := cb.status, cb.history, cb.reliability
+	cb.mx.Unlock()
+
+	if history != nil {
+		history.addRejection(status, fallback)
+	}
+
+	if reliability != nil {
+		reliability.addRejection(fallback)
 	}
 }
 
@@ -131,6 +311,11 @@ func (cb *CircuitBreaker[TRequest, TResponse]) addResponse(response bool) {
 }
 
 func (cb *CircuitBreaker[TRequest, TResponse]) handleStatus(successesCount, errorsCount int64) {
+	// пока статус переопределен вручную, автоматические переходы не выполняются
+	if cb.overridden() {
+		return
+	}
+
 	if cb.status == StatusHalfOpen && successesCount >= cb.halfOpenLimit {
 		cb.setStatus(StatusClosed)
 	}
@@ -141,14 +326,67 @@ func (cb *CircuitBreaker[TRequest, TResponse]) handleStatus(successesCount, erro
 
 		go cb.recover()
 	}
+
+	cb.handleWarning(errorsPercentage)
 }
 
 func (cb *CircuitBreaker[TRequest, TResponse]) setStatus(status Status) {
+	cb.transition(status, false)
+}
+
+// transition - смена статуса. manual - переход по ручному переопределению:
+// он не считается срабатыванием или восстановлением
+func (cb *CircuitBreaker[TRequest, TResponse]) transition(status Status, manual bool) {
 	cb.mx.Lock()
-	defer cb.mx.Unlock()
+
+	from := cb.status
+
+	if cb.reliability != nil && from != status {
+		cb.reliability.addTransition(from, status, manual)
+	}
+
+	if status == StatusOpen && from != StatusOpen && !manual {
+		cb.trips++
+	}
 
 	cb.status = status
 	cb.responses = make([]bool, 0, cb.responsesThreshold+1)
+
+	var listeners []func(from, to Status)
+	if from != status {
+		listeners = make([]func(from, to Status), 0, len(cb.listeners))
+		for _, fn := range cb.listeners {
+			listeners = append(listeners, fn)
+		}
+	}
+
+	cb.mx.Unlock()
+
+	for _, fn := range listeners {
+		fn(from, status)
+	}
+}
+
+// onTransition - подписывает fn на смену статуса и возвращает функцию отписки.
+// fn вызывается после смены статуса без блокировки предохранителя и не должен выполняться долго
+func (cb *CircuitBreaker[TRequest, TResponse]) onTransition(fn func(from, to Status)) func() {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	if cb.listeners == nil {
+		cb.listeners = make(map[int64]func(from, to Status))
+	}
+
+	id := cb.nextListener
+	cb.nextListener++
+	cb.listeners[id] = fn
+
+	return func() {
+		cb.mx.Lock()
+		defer cb.mx.Unlock()
+
+		delete(cb.listeners, id)
+	}
 }
 
 func (cb *CircuitBreaker[TRequest, TResponse]) recover() {
@@ -156,7 +394,7 @@ func (cb *CircuitBreaker[TRequest, TResponse]) recover() {
 
 	cb.mx.Lock()
 
-	if cb.status == StatusOpen {
+	if cb.status == StatusOpen && cb.override == nil {
 		cb.mx.Unlock()
 
 		cb.setStatus(StatusHalfOpen)
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ComparisonStats - поведение одной конфигурации предохранителя за период сравнения
+type ComparisonStats struct {
+	// Rejections - запросы, которые конфигурация отклонила (для candidate - отклонила бы)
+	Rejections int64 `json:"rejections"`
+	// MissedFailures - упавшие запросы, которые конфигурация пропустила к зависимости
+	MissedFailures int64 `json:"missed_failures"`
+	// Trips - переходы в статус opened
+	Trips int64 `json:"trips"`
+}
+
+// ComparisonReport - сравнение текущей (primary) и новой (candidate) конфигураций на одном потоке запросов
+type ComparisonReport struct {
+	Since     time.Time       `json:"since"`
+	Calls     int64           `json:"calls"`
+	Primary   ComparisonStats `json:"primary"`
+	Candidate ComparisonStats `json:"candidate"`
+	// Disagreements - запросы, которые одна конфигурация пропустила, а другая отклонила
+	Disagreements int64 `json:"disagreements"`
+	// Unknown - запросы, которые primary бросил по своему timeout, а candidate с большим timeout мог бы дождаться
+	Unknown int64 `json:"unknown"`
+}
+
+// Comparison - прогоняет запросы через primary, а candidate только наблюдает те же исходы.
+// Решения принимает только primary, candidate считает, что бы он сделал на его месте.
+// Исходы запросов, которые отклонил primary, неизвестны, поэтому candidate их не получает.
+// Запрос выполняется с timeout primary: более медленный ответ candidate с меньшим timeout считает неудачей,
+// а если primary бросил запрос по timeout, который меньше timeout candidate, исход для candidate неизвестен
+// и учитывается только в Unknown
+type Comparison[TRequest, TResponse any] struct {
+	primary   *CircuitBreaker[TRequest, TResponse]
+	candidate *CircuitBreaker[TRequest, TResponse]
+
+	mx     sync.Mutex
+	report ComparisonReport
+	// primaryTrips, candidateTrips - значения Trips на момент начала сравнения
+	primaryTrips   int64
+	candidateTrips int64
+}
+
+func NewComparison[TRequest, TResponse any](primary, candidate *CircuitBreaker[TRequest, TResponse]) *Comparison[TRequest, TResponse] {
+	return &Comparison[TRequest, TResponse]{
+		primary:        primary,
+		candidate:      candidate,
+		report:         ComparisonReport{Since: time.Now()},
+		primaryTrips:   primary.Trips(),
+		candidateTrips: candidate.Trips(),
+	}
+}
+
+func (c *Comparison[TRequest, TResponse]) Execute(ctx context.Context, params TRequest, f func(context.Context, TRequest) (TResponse, error)) (TResponse, error) {
+	c.mx.Lock()
+	candidateRejects := c.candidate.Status() == StatusOpen
+	c.mx.Unlock()
+
+	start := time.Now()
+
+	// ответ fallback приходит без ошибки, поэтому об отклонении сообщает сам primary
+	result, primaryRejected, err := c.primary.execute(ctx, params, f)
+
+	// candidate со своим timeout не дождался бы более медленного ответа
+	candidateSuccess := err == nil && time.Since(start) <= c.candidate.timeout
+
+	// primary бросил запрос по своему timeout, а candidate ждал бы дольше
+	candidateUnknown := errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil &&
+		c.candidate.timeout > c.primary.timeout
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.report.Calls++
+
+	if primaryRejected {
+		c.report.Primary.Rejections++
+	} else if err != nil {
+		c.report.Primary.MissedFailures++
+	}
+
+	if candidateRejects {
+		c.report.Candidate.Rejections++
+	} else if candidateUnknown {
+		c.report.Unknown++
+	} else if !primaryRejected {
+		if !candidateSuccess {
+			c.report.Candidate.MissedFailures++
+		}
+
+		c.candidate.handleResponse(candidateSuccess)
+	}
+
+	if primaryRejected != candidateRejects {
+		c.report.Disagreements++
+	}
+
+	return result, err
+}
+
+// Report - сравнение с момента создания Comparison
+func (c *Comparison[TRequest, TResponse]) Report() ComparisonReport {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	report := c.report
+	report.Primary.Trips = c.primary.Trips() - c.primaryTrips
+	report.Candidate.Trips = c.candidate.Trips() - c.candidateTrips
+
+	return report
+}
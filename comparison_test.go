@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestComparison_Execute(t *testing.T) {
+	primary := NewCB[bool, string](time.Second, time.Minute, 75, 1, 4)
+	candidate := NewCB[bool, string](time.Second, time.Minute, 50, 1, 2)
+
+	c := NewComparison(primary, candidate)
+
+	f := func(_ context.Context, fail bool) (string, error) {
+		if fail {
+			return "", errors.New("fail")
+		}
+
+		return "ok", nil
+	}
+
+	ctx := context.Background()
+
+	for _, fail := range []bool{false, true, true, true, false} {
+		_, _ = c.Execute(ctx, fail, f)
+	}
+
+	report := c.Report()
+
+	if report.Calls != 5 || report.Disagreements != 2 {
+		t.Errorf("Got calls=%d disagreements=%d", report.Calls, report.Disagreements)
+	}
+
+	wantPrimary := ComparisonStats{Rejections: 1, MissedFailures: 3, Trips: 1}
+	if report.Primary != wantPrimary {
+		t.Errorf("Wanted primary %+v, but got %+v", wantPrimary, report.Primary)
+	}
+
+	wantCandidate := ComparisonStats{Rejections: 3, MissedFailures: 1, Trips: 1}
+	if report.Candidate != wantCandidate {
+		t.Errorf("Wanted candidate %+v, but got %+v", wantCandidate, report.Candidate)
+	}
+}
+
+func TestComparison_Execute_Candidate_Timeout(t *testing.T) {
+	primary := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+	candidate := NewCB[time.Duration, string](20*time.Millisecond, time.Minute, 50, 1, 1)
+
+	c := NewComparison(primary, candidate)
+
+	// primary дожидается ответа, а candidate со своим timeout счел бы его неудачей
+	if _, err := c.Execute(context.Background(), 50*time.Millisecond, F); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	report := c.Report()
+
+	wantCandidate := ComparisonStats{MissedFailures: 1, Trips: 1}
+	if report.Candidate != wantCandidate {
+		t.Errorf("Wanted candidate %+v, but got %+v", wantCandidate, report.Candidate)
+	}
+
+	if report.Primary != (ComparisonStats{}) {
+		t.Errorf("Wanted no primary failures, but got %+v", report.Primary)
+	}
+
+	// primary бросает запрос по своему timeout, а candidate дождался бы ответа - исход для него неизвестен
+	primary = NewCB[time.Duration, string](20*time.Millisecond, time.Minute, 50, 1, 1)
+	candidate = NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+
+	c = NewComparison(primary, candidate)
+
+	if _, err := c.Execute(context.Background(), 50*time.Millisecond, F); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wanted deadline exceeded, but got %v", err)
+	}
+
+	report = c.Report()
+
+	if report.Candidate != (ComparisonStats{}) || candidate.Status() != StatusClosed {
+		t.Errorf("Wanted candidate not to count primary timeout, but got %+v", report.Candidate)
+	}
+
+	wantPrimary := ComparisonStats{MissedFailures: 1, Trips: 1}
+	if report.Primary != wantPrimary || report.Unknown != 1 {
+		t.Errorf("Wanted primary %+v and 1 unknown, but got %+v and %d", wantPrimary, report.Primary, report.Unknown)
+	}
+}
+
+type staticFallback string
+
+func (s staticFallback) Fallback(context.Context, bool) (string, bool) {
+	return string(s), true
+}
+
+func TestComparison_Execute_Fallback(t *testing.T) {
+	primary := NewCB[bool, string](time.Second, time.Minute, 50, 1, 1)
+	primary.SetFallback(staticFallback("cached"))
+
+	candidate := NewCB[bool, string](time.Second, time.Minute, 50, 1, 1)
+
+	c := NewComparison(primary, candidate)
+
+	f := func(_ context.Context, fail bool) (string, error) {
+		if fail {
+			return "", errors.New("fail")
+		}
+
+		return "ok", nil
+	}
+
+	ctx := context.Background()
+
+	_, _ = c.Execute(ctx, true, f)
+
+	// ответ fallback - все равно отклонение, исход запроса candidate не получает
+	if result, err := c.Execute(ctx, false, f); err != nil || result != "cached" {
+		t.Fatalf("Wanted fallback answer, but got %q, %v", result, err)
+	}
+
+	report := c.Report()
+
+	wantPrimary := ComparisonStats{Rejections: 1, MissedFailures: 1, Trips: 1}
+	if report.Primary != wantPrimary {
+		t.Errorf("Wanted primary %+v, but got %+v", wantPrimary, report.Primary)
+	}
+
+	wantCandidate := ComparisonStats{Rejections: 1, MissedFailures: 1, Trips: 1}
+	if report.Candidate != wantCandidate {
+		t.Errorf("Wanted candidate %+v, but got %+v", wantCandidate, report.Candidate)
+	}
+
+	if report.Disagreements != 0 {
+		t.Errorf("Wanted no disagreements, but got %d", report.Disagreements)
+	}
+}
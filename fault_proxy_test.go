@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type proxyMode int
+
+const (
+	// proxyPass - данные передаются в обе стороны
+	proxyPass proxyMode = iota
+	// proxyReset - соединения сбрасываются (RST)
+	proxyReset
+	// proxyBlackhole - данные в обе стороны молча теряются, соединения не закрываются
+	proxyBlackhole
+	// proxyHalfOpen - запросы доходят до сервера, но ответы клиенту теряются, соединения не закрываются
+	proxyHalfOpen
+)
+
+// faultProxy - TCP прокси между клиентом и локальным сервером, который умеет вносить сетевые неполадки
+type faultProxy struct {
+	listener net.Listener
+	target   string
+
+	mx sync.Mutex
+	// latency - задержка перед отправкой каждого куска данных
+	latency time.Duration
+	// bandwidth - лимит скорости в байтах в секунду в каждую сторону (0 - без лимита)
+	bandwidth int
+	mode      proxyMode
+	conns     map[net.Conn]struct{}
+
+	wg sync.WaitGroup
+}
+
+func newFaultProxy(t *testing.T, target string) *faultProxy {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	p := &faultProxy{
+		listener: listener,
+		target:   target,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.accept()
+
+	t.Cleanup(p.close)
+
+	return p
+}
+
+func (p *faultProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *faultProxy) SetLatency(latency time.Duration) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.latency = latency
+}
+
+func (p *faultProxy) SetBandwidth(bytesPerSecond int) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.bandwidth = bytesPerSecond
+}
+
+// SetMode - переключает режим. При переходе в proxyReset открытые соединения тоже сбрасываются
+func (p *faultProxy) SetMode(mode proxyMode) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.mode = mode
+
+	if mode == proxyReset {
+		for conn := range p.conns {
+			reset(conn)
+		}
+	}
+}
+
+func (p *faultProxy) settings() (time.Duration, int, proxyMode) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.latency, p.bandwidth, p.mode
+}
+
+func (p *faultProxy) accept() {
+	defer p.wg.Done()
+
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		p.wg.Add(1)
+		go p.handle(client)
+	}
+}
+
+func (p *faultProxy) handle(client net.Conn) {
+	defer p.wg.Done()
+
+	if !p.track(client) {
+		return
+	}
+	defer p.untrack(client)
+
+	_, _, mode := p.settings()
+
+	switch mode {
+	case proxyReset:
+		reset(client)
+		return
+	case proxyBlackhole:
+		_, _ = io.Copy(io.Discard, client)
+		return
+	}
+
+	server, err := net.Dial("tcp", p.target)
+	if err != nil {
+		reset(client)
+		return
+	}
+
+	if !p.track(server) {
+		return
+	}
+	defer p.untrack(server)
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		p.pipe(server, client, false)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		p.pipe(client, server, true)
+		done <- struct{}{}
+	}()
+
+	<-done
+	_ = client.Close()
+	_ = server.Close()
+	<-done
+}
+
+// pipe - копирует данные из src в dst с учетом текущих неполадок
+func (p *faultProxy) pipe(dst, src net.Conn, toClient bool) {
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if !p.forward(dst, buf[:n], toClient) {
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *faultProxy) forward(dst net.Conn, data []byte, toClient bool) bool {
+	latency, bandwidth, mode := p.settings()
+
+	switch {
+	case mode == proxyReset:
+		return false
+	case mode == proxyBlackhole, mode == proxyHalfOpen && toClient:
+		return true
+	}
+
+	time.Sleep(latency)
+
+	if bandwidth <= 0 {
+		_, err := dst.Write(data)
+		return err == nil
+	}
+
+	// отправляем кусками по 1/10 лимита, чтобы скорость была равномерной
+	chunk := bandwidth / 10
+	if chunk < 1 {
+		chunk = 1
+	}
+
+	for len(data) > 0 {
+		n := min(chunk, len(data))
+
+		if _, err := dst.Write(data[:n]); err != nil {
+			return false
+		}
+
+		time.Sleep(time.Duration(n) * time.Second / time.Duration(bandwidth))
+		data = data[n:]
+	}
+
+	return true
+}
+
+func (p *faultProxy) track(conn net.Conn) bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.conns == nil {
+		_ = conn.Close()
+		return false
+	}
+
+	p.conns[conn] = struct{}{}
+
+	return true
+}
+
+func (p *faultProxy) untrack(conn net.Conn) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	_ = conn.Close()
+	delete(p.conns, conn)
+}
+
+func (p *faultProxy) close() {
+	_ = p.listener.Close()
+
+	p.mx.Lock()
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.conns = nil
+	p.mx.Unlock()
+
+	p.wg.Wait()
+}
+
+// reset - закрывает соединение с RST вместо FIN
+func reset(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+
+	_ = conn.Close()
+}
+
+func TestFaultProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	defer server.Close()
+
+	proxy := newFaultProxy(t, server.Listener.Addr().String())
+
+	client := &http.Client{}
+	url := "http://" + proxy.Addr()
+
+	get := func(ctx context.Context, url string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+
+		return string(body), err
+	}
+
+	testCases := []struct {
+		name       string
+		setup      func()
+		mustFail   bool
+		minLatency time.Duration
+	}{
+		{
+			name:  "Success",
+			setup: func() {},
+		},
+		{
+			name:       "Success_Latency",
+			setup:      func() { proxy.SetLatency(100 * time.Millisecond) },
+			minLatency: 100 * time.Millisecond,
+		},
+		{
+			name: "Success_Bandwidth",
+			setup: func() {
+				proxy.SetLatency(0)
+				proxy.SetBandwidth(8 * 1024)
+			},
+			minLatency: 200 * time.Millisecond,
+		},
+		{
+			name: "Fail_Reset",
+			setup: func() {
+				proxy.SetBandwidth(0)
+				proxy.SetMode(proxyReset)
+			},
+			mustFail: true,
+		},
+		{
+			name:     "Fail_Blackhole",
+			setup:    func() { proxy.SetMode(proxyBlackhole) },
+			mustFail: true,
+		},
+		{
+			name:     "Fail_Half_Open",
+			setup:    func() { proxy.SetMode(proxyHalfOpen) },
+			mustFail: true,
+		},
+		{
+			name:  "Success_Recovered",
+			setup: func() { proxy.SetMode(proxyPass) },
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Running test case: %s", testCase.name)
+
+		testCase.setup()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		start := time.Now()
+
+		_, err := get(ctx, url)
+
+		cancel()
+
+		if !testCase.mustFail && err != nil {
+			t.Errorf("Got error: %s", err.Error())
+			continue
+		}
+
+		if testCase.mustFail && err == nil {
+			t.Errorf("Wanted error, but got nil")
+			continue
+		}
+
+		if elapsed := time.Since(start); elapsed < testCase.minLatency {
+			t.Errorf("Wanted at least %s, but took %s", testCase.minLatency, elapsed)
+		}
+	}
+}
+
+func TestCircuitBreaker_Execute_FaultProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	proxy := newFaultProxy(t, server.Listener.Addr().String())
+
+	client := &http.Client{}
+
+	get := func(ctx context.Context, url string) (int, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+
+		_, err = io.Copy(io.Discard, resp.Body)
+
+		return resp.StatusCode, err
+	}
+
+	cb := NewCB[string, int](200*time.Millisecond, time.Minute, 50, 1, 2)
+	ctx := context.Background()
+	url := "http://" + proxy.Addr()
+
+	if _, err := cb.Execute(ctx, url, get); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	// соединение из пула клиента повиснет: ответ сервера до клиента не дойдет
+	proxy.SetMode(proxyHalfOpen)
+
+	if _, err := cb.Execute(ctx, url, get); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wanted %s, but got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, err := cb.Execute(ctx, url, get); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FixtureDefaultKey - ключ фикстуры, которая отдается, если для запроса нет своей
+const FixtureDefaultKey = "*"
+
+// FixtureFallback - Fallback, который отдает заранее подготовленные ответы из JSON файла.
+// Файл - объект, где ключ - результат match для запроса, а значение - ответ TResponse:
+//
+//	{"user-1": {...}, "*": {...}}
+type FixtureFallback[TRequest, TResponse any] struct {
+	path string
+	// match - ключ фикстуры для запроса
+	match func(TRequest) string
+
+	mx       sync.RWMutex
+	fixtures map[string]TResponse
+	modTime  time.Time
+}
+
+func NewFixtureFallback[TRequest, TResponse any](path string, match func(TRequest) string) (*FixtureFallback[TRequest, TResponse], error) {
+	f := &FixtureFallback[TRequest, TResponse]{
+		path:  path,
+		match: match,
+	}
+
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *FixtureFallback[TRequest, TResponse]) Fallback(_ context.Context, params TRequest) (TResponse, bool) {
+	f.mx.RLock()
+	defer f.mx.RUnlock()
+
+	if result, ok := f.fixtures[f.match(params)]; ok {
+		return result, true
+	}
+
+	result, ok := f.fixtures[FixtureDefaultKey]
+
+	return result, ok
+}
+
+// Reload - перечитывает файл. При ошибке остаются фикстуры, загруженные ранее
+func (f *FixtureFallback[TRequest, TResponse]) Reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("stat fixtures: %w", err)
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("read fixtures: %w", err)
+	}
+
+	fixtures := make(map[string]TResponse)
+	if err = json.Unmarshal(data, &fixtures); err != nil {
+		return fmt.Errorf("decode fixtures %s: %w", f.path, err)
+	}
+
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.fixtures = fixtures
+	f.modTime = info.ModTime()
+
+	return nil
+}
+
+// Watch - раз в interval проверяет время изменения файла и перечитывает его, пока ctx не отменен.
+// Ошибки перезагрузки передаются в onError (может быть nil)
+func (f *FixtureFallback[TRequest, TResponse]) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// failed - время изменения файла, который не удалось перечитать.
+	// Повторно он перечитывается только после следующего изменения
+	var failed time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(f.path)
+		if err == nil {
+			f.mx.RLock()
+			changed := !info.ModTime().Equal(f.modTime)
+			f.mx.RUnlock()
+
+			if !changed || info.ModTime().Equal(failed) {
+				continue
+			}
+
+			if err = f.Reload(); err != nil {
+				failed = info.ModTime()
+			}
+		}
+
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
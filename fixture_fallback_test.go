@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Recommendations struct {
+	Items []string `json:"items"`
+}
+
+func TestFixtureFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+
+	if err := os.WriteFile(path, []byte(`{"vip": {"items": ["a", "b"]}, "*": {"items": []}}`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	fb, err := NewFixtureFallback[string, Recommendations](path, func(user string) string { return user })
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	cb := NewCB[string, Recommendations](time.Second, time.Minute, 50, 1, 1)
+	cb.SetFallback(fb)
+
+	r := NewReliabilityRecorder()
+	cb.SetReliability(r)
+
+	h, err := NewHistory()
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	cb.SetHistory(h)
+
+	ctx := context.Background()
+
+	failing := func(context.Context, string) (Recommendations, error) {
+		return Recommendations{}, errors.New("fail")
+	}
+
+	if _, err = cb.Execute(ctx, "vip", failing); err == nil {
+		t.Fatalf("Wanted error, but got nil")
+	}
+
+	result, err := cb.Execute(ctx, "vip", failing)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if len(result.Items) != 2 {
+		t.Errorf("Wanted vip fixture, but got %+v", result)
+	}
+
+	result, err = cb.Execute(ctx, "someone", failing)
+	if err != nil || result.Items == nil || len(result.Items) != 0 {
+		t.Errorf("Wanted default fixture, but got %+v, %v", result, err)
+	}
+
+	report := r.Report()
+	if report.Rejected != 2 || report.Fallbacks != 2 {
+		t.Errorf("Wanted 2 fallback hits, but got %+v", report)
+	}
+
+	var fallbacks int64
+	for _, p := range h.Query(time.Now().Add(-time.Minute), time.Now()) {
+		fallbacks += p.Fallbacks
+	}
+
+	if fallbacks != 2 {
+		t.Errorf("Wanted 2 fallback hits in history, but got %d", fallbacks)
+	}
+
+	// без фикстуры по умолчанию запрос отклоняется как обычно
+	if err = os.WriteFile(path, []byte(`{"vip": {"items": ["c"]}}`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if err = fb.Reload(); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if _, err = cb.Execute(ctx, "someone", failing); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	// битый файл не затирает загруженные фикстуры
+	if err = os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if err = fb.Reload(); err == nil {
+		t.Errorf("Wanted error, but got nil")
+	}
+
+	result, err = cb.Execute(ctx, "vip", failing)
+	if err != nil || len(result.Items) != 1 || result.Items[0] != "c" {
+		t.Errorf("Wanted reloaded vip fixture, but got %+v, %v", result, err)
+	}
+}
+
+func TestFixtureFallback_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+
+	if err := os.WriteFile(path, []byte(`{"*": "old"}`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	fb, err := NewFixtureFallback[string, string](path, func(key string) string { return key })
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go fb.Watch(ctx, 10*time.Millisecond, nil)
+
+	if err = os.WriteFile(path, []byte(`{"*": "new"}`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	// время изменения может совпасть с предыдущим на файловых системах с грубым разрешением
+	if err = os.Chtimes(path, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if result, _ := fb.Fallback(ctx, "any"); result == "new" {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("Fixtures were not reloaded")
+}
+
+func TestFixtureFallback_Watch_Broken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+
+	if err := os.WriteFile(path, []byte(`{"*": "old"}`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	fb, err := NewFixtureFallback[string, string](path, func(key string) string { return key })
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var failures atomic.Int64
+	go fb.Watch(ctx, 10*time.Millisecond, func(error) { failures.Add(1) })
+
+	if err = os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if err = os.Chtimes(path, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	// битый файл перечитывается один раз, а не на каждой проверке
+	time.Sleep(100 * time.Millisecond)
+
+	if failures.Load() != 1 {
+		t.Fatalf("Wanted 1 reload error, but got %d", failures.Load())
+	}
+
+	if err = os.WriteFile(path, []byte(`{"*": "new"}`), 0o600); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if err = os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if result, _ := fb.Fallback(ctx, "any"); result == "new" {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("Fixtures were not reloaded after the file was fixed")
+}
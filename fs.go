@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrFSFallback - предохранитель FS подменил ответ через fallback, а FS не знает, как его отдать
+	ErrFSFallback = errors.New("fallback answers are not supported by FS")
+	// ErrFileAbandoned - предыдущее чтение файла брошено по таймауту и может сдвинуть позицию в нем в любой момент
+	ErrFileAbandoned = errors.New("previous file read was abandoned, file position is unknown")
+)
+
+// FSTimeouts - лимиты на отдельные операции с файловой системой (0 - только timeout предохранителя)
+type FSTimeouts struct {
+	Open    time.Duration
+	Read    time.Duration
+	Stat    time.Duration
+	ReadDir time.Duration
+}
+
+// FS - fs.FS, операции которой выполняются через предохранитель. Рассчитана на сетевые файловые системы
+// (NFS, FUSE), где чтение может зависнуть на минуты: зависшая операция бросается по таймауту
+// и учитывается в Abandoned, а пока предохранитель в статусе opened, операции сразу завершаются ошибкой.
+// После брошенного чтения файл больше не читается (ErrFileAbandoned): позиция в нем неизвестна.
+// Ошибки вроде fs.ErrNotExist и io.EOF - нормальные ответы файловой системы и неудачей не считаются
+type FS struct {
+	fsys     fs.FS
+	cb       *CircuitBreaker[string, any]
+	timeouts FSTimeouts
+}
+
+var (
+	_ fs.FS        = (*FS)(nil)
+	_ fs.StatFS    = (*FS)(nil)
+	_ fs.ReadDirFS = (*FS)(nil)
+)
+
+func NewFS(fsys fs.FS, cb *CircuitBreaker[string, any], timeouts FSTimeouts) *FS {
+	return &FS{
+		fsys:     fsys,
+		cb:       cb,
+		timeouts: timeouts,
+	}
+}
+
+func (f *FS) Open(name string) (fs.File, error) {
+	v, err := f.do("open", name, f.timeouts.Open, func() (any, error) {
+		return f.fsys.Open(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opened, ok := v.(fs.File)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrFSFallback}
+	}
+
+	return &file{fs: f, name: name, file: opened}, nil
+}
+
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	v, err := f.do("stat", name, f.timeouts.Stat, func() (any, error) {
+		return fs.Stat(f.fsys, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := v.(fs.FileInfo)
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: ErrFSFallback}
+	}
+
+	return info, nil
+}
+
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	v, err := f.do("readdir", name, f.timeouts.ReadDir, func() (any, error) {
+		return fs.ReadDir(f.fsys, name)
+	})
+
+	entries, _ := v.([]fs.DirEntry)
+
+	return entries, err
+}
+
+// Abandoned - сколько операций не завершились к таймауту и все еще висят
+func (f *FS) Abandoned() int64 {
+	return f.cb.Abandoned()
+}
+
+// fsResult - ответ файловой системы, включая ошибки, которые не считаются неудачей
+type fsResult struct {
+	value any
+	err   error
+}
+
+func (f *FS) do(op, name string, timeout time.Duration, fn func() (any, error)) (any, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	v, err := f.cb.Execute(ctx, name, func(context.Context, string) (any, error) {
+		value, err := fn()
+		if err != nil && !expectedFSError(err) {
+			return nil, err
+		}
+
+		return fsResult{value: value, err: err}, nil
+	})
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return nil, err
+		}
+
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	result, ok := v.(fsResult)
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: ErrFSFallback}
+	}
+
+	return result.value, result.err
+}
+
+func expectedFSError(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, fs.ErrNotExist) ||
+		errors.Is(err, fs.ErrExist) ||
+		errors.Is(err, fs.ErrPermission) ||
+		errors.Is(err, fs.ErrInvalid)
+}
+
+type file struct {
+	fs   *FS
+	name string
+	file fs.File
+	// broken - чтение было брошено по таймауту: оно может продолжаться и сдвинуть позицию в файле,
+	// поэтому следующие чтения пропустили бы данные или выполнялись бы одновременно с ним
+	broken atomic.Bool
+}
+
+var _ fs.ReadDirFile = (*file)(nil)
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.broken.Load() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: ErrFileAbandoned}
+	}
+
+	// брошенное по таймауту чтение может завершиться позже, поэтому читаем в свой буфер, а не в p
+	v, err := f.fs.do("read", f.name, f.fs.timeouts.Read, func() (any, error) {
+		buf := make([]byte, len(p))
+
+		n, err := f.file.Read(buf)
+
+		return buf[:n], err
+	})
+
+	f.checkAbandoned(err)
+
+	buf, _ := v.([]byte)
+
+	return copy(p, buf), err
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	v, err := f.fs.do("stat", f.name, f.fs.timeouts.Stat, func() (any, error) {
+		return f.file.Stat()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, ok := v.(fs.FileInfo)
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: ErrFSFallback}
+	}
+
+	return info, nil
+}
+
+func (f *file) ReadDir(n int) ([]fs.DirEntry, error) {
+	dir, ok := f.file.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not implemented")}
+	}
+
+	if f.broken.Load() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: ErrFileAbandoned}
+	}
+
+	v, err := f.fs.do("readdir", f.name, f.fs.timeouts.ReadDir, func() (any, error) {
+		return dir.ReadDir(n)
+	})
+
+	f.checkAbandoned(err)
+
+	entries, _ := v.([]fs.DirEntry)
+
+	return entries, err
+}
+
+// checkAbandoned - помечает файл сломанным, если операция завершилась по таймауту
+// и брошенный вызов продолжает выполняться
+func (f *file) checkAbandoned(err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		f.broken.Store(true)
+	}
+}
+
+func (f *file) Close() error {
+	return f.file.Close()
+}
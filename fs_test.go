@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// slowFS - файловая система, у которой зависают операции с файлами из hang, пока не закрыт release
+type slowFS struct {
+	fsys    fstest.MapFS
+	hang    map[string]bool
+	release chan struct{}
+}
+
+type slowFile struct {
+	fs.File
+	slow    bool
+	release chan struct{}
+}
+
+func (s *slowFS) Open(name string) (fs.File, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &slowFile{File: f, slow: s.hang[name], release: s.release}, nil
+}
+
+func (s *slowFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return s.fsys.ReadDir(name)
+}
+
+func (f *slowFile) Read(p []byte) (int, error) {
+	if f.slow {
+		<-f.release
+	}
+
+	return f.File.Read(p)
+}
+
+func TestFS(t *testing.T) {
+	slow := &slowFS{
+		fsys: fstest.MapFS{
+			"dir/ok.txt":   {Data: []byte("hello")},
+			"dir/hang.txt": {Data: []byte("stuck")},
+		},
+		hang:    map[string]bool{"dir/hang.txt": true},
+		release: make(chan struct{}),
+	}
+
+	cb := NewCB[string, any](time.Second, time.Minute, 50, 1, 2)
+	fsys := NewFS(slow, cb, FSTimeouts{Read: 50 * time.Millisecond})
+
+	data, err := fs.ReadFile(fsys, "dir/ok.txt")
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Wanted hello, but got %q, %v", data, err)
+	}
+
+	entries, err := fs.ReadDir(fsys, "dir")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Wanted 2 entries, but got %v, %v", entries, err)
+	}
+
+	// отсутствующий файл - обычный ответ, а не неудача
+	if _, err = fsys.Stat("dir/missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Wanted %s, but got %v", fs.ErrNotExist, err)
+	}
+
+	if cb.Status() != StatusClosed {
+		t.Fatalf("Wanted status %s, but got %s", StatusClosed, cb.Status())
+	}
+
+	f, err := fsys.Open("dir/hang.txt")
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	defer f.Close()
+
+	start := time.Now()
+
+	_, err = f.Read(make([]byte, 16))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wanted deadline exceeded, but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Stuck read was not abandoned, took %s", elapsed)
+	}
+
+	if fsys.Abandoned() != 1 {
+		t.Errorf("Wanted 1 abandoned operation, but got %d", fsys.Abandoned())
+	}
+
+	// предохранитель сработал - операции сразу завершаются ошибкой
+	if _, err = fs.ReadFile(fsys, "dir/ok.txt"); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || pathErr.Op != "open" {
+		t.Errorf("Wanted *fs.PathError for open, but got %v", err)
+	}
+
+	close(slow.release)
+
+	deadline := time.Now().Add(time.Second)
+	for fsys.Abandoned() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if fsys.Abandoned() != 0 {
+		t.Errorf("Wanted abandoned operation to finish, but got %d", fsys.Abandoned())
+	}
+}
+
+func TestFS_Read_EOF(t *testing.T) {
+	cb := NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+	fsys := NewFS(fstest.MapFS{"a.txt": {Data: []byte("a")}}, cb, FSTimeouts{})
+
+	f, err := fsys.Open("a.txt")
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "a" {
+		t.Fatalf("Wanted a, but got %q, %v", data, err)
+	}
+
+	if cb.Status() != StatusClosed {
+		t.Errorf("Wanted io.EOF not to count as failure, but status is %s", cb.Status())
+	}
+}
+
+func TestFS_Read_After_Timeout(t *testing.T) {
+	slow := &slowFS{
+		fsys:    fstest.MapFS{"hang.txt": {Data: []byte("stuck")}},
+		hang:    map[string]bool{"hang.txt": true},
+		release: make(chan struct{}),
+	}
+
+	// одного брошенного чтения недостаточно, чтобы предохранитель сработал
+	cb := NewCB[string, any](time.Second, time.Minute, 60, 1, 4)
+	fsys := NewFS(slow, cb, FSTimeouts{Read: 50 * time.Millisecond})
+
+	f, err := fsys.Open("hang.txt")
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	defer f.Close()
+
+	if _, err = f.Read(make([]byte, 2)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wanted deadline exceeded, but got %v", err)
+	}
+
+	// брошенное чтение завершается и сдвигает позицию в файле
+	close(slow.release)
+
+	deadline := time.Now().Add(time.Second)
+	for fsys.Abandoned() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	n, err := f.Read(make([]byte, 16))
+	if n != 0 || !errors.Is(err, ErrFileAbandoned) {
+		t.Errorf("Wanted %s, but got %d bytes, %v", ErrFileAbandoned, n, err)
+	}
+
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || pathErr.Op != "read" {
+		t.Errorf("Wanted *fs.PathError for read, but got %v", err)
+	}
+
+	if cb.Status() != StatusClosed {
+		t.Errorf("Wanted status %s, but got %s", StatusClosed, cb.Status())
+	}
+}
+
+type anyFallback struct{}
+
+func (anyFallback) Fallback(context.Context, string) (any, bool) {
+	return "fallback", true
+}
+
+func TestFS_Fallback(t *testing.T) {
+	cb := NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+	cb.SetFallback(anyFallback{})
+
+	fsys := NewFS(fstest.MapFS{"a.txt": {Data: []byte("a")}}, cb, FSTimeouts{})
+
+	_, _ = cb.Execute(context.Background(), "a.txt", func(context.Context, string) (any, error) {
+		return nil, errors.New("fail")
+	})
+
+	if _, err := fs.ReadFile(fsys, "a.txt"); !errors.Is(err, ErrFSFallback) {
+		t.Errorf("Wanted %s, but got %v", ErrFSFallback, err)
+	}
+
+	if _, err := fsys.Stat("a.txt"); !errors.Is(err, ErrFSFallback) {
+		t.Errorf("Wanted %s, but got %v", ErrFSFallback, err)
+	}
+}
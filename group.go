@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GroupError - итог выполнения группы, если хотя бы одна задача не выполнилась
+type GroupError struct {
+	// Err - первая ошибка задачи, не связанная со срабатыванием предохранителя,
+	// или причина отмены родительского контекста, из-за которой задачи не запускались
+	Err error
+	// Rejected - задачи, которые отклонил предохранитель или которые не запускались после его срабатывания
+	Rejected []string
+	// Cancelled - задачи, которые выполнялись в момент срабатывания и были отменены
+	Cancelled []string
+}
+
+func (e *GroupError) Error() string {
+	parts := make([]string, 0, 3)
+
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+
+	if len(e.Rejected) > 0 {
+		parts = append(parts, fmt.Sprintf("rejected: %s", strings.Join(e.Rejected, ", ")))
+	}
+
+	if len(e.Cancelled) > 0 {
+		parts = append(parts, fmt.Sprintf("cancelled: %s", strings.Join(e.Cancelled, ", ")))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func (e *GroupError) Unwrap() []error {
+	errs := make([]error, 0, 2)
+
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+
+	if len(e.Rejected) > 0 || len(e.Cancelled) > 0 {
+		errs = append(errs, ErrCircuitOpened)
+	}
+
+	return errs
+}
+
+// Group - аналог errgroup.Group, задачи которого выполняются через предохранитель.
+// Когда предохранитель переходит в статус opened, контекст группы отменяется,
+// а задачи в очереди не запускаются
+type Group[TRequest, TResponse any] struct {
+	cb     *CircuitBreaker[TRequest, TResponse]
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	// unsubscribe - отписка от смены статуса cb
+	unsubscribe func()
+	wg          sync.WaitGroup
+	// sem - ограничение на количество одновременно выполняемых задач (nil - без ограничения)
+	sem chan struct{}
+
+	mx        sync.Mutex
+	err       error
+	rejected  []string
+	cancelled []string
+}
+
+// NewGroup - группа и ее контекст, который отменяется при срабатывании cb
+func NewGroup[TRequest, TResponse any](ctx context.Context, cb *CircuitBreaker[TRequest, TResponse]) (*Group[TRequest, TResponse], context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	g := &Group[TRequest, TResponse]{
+		cb:     cb,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	// предохранитель может сработать из-за чужих запросов, пока задачи группы выполняются
+	g.unsubscribe = cb.onTransition(func(_, to Status) {
+		if to == StatusOpen {
+			g.cancel(ErrCircuitOpened)
+		}
+	})
+
+	return g, ctx
+}
+
+// SetLimit - не больше n задач выполняются одновременно, остальные ждут в очереди.
+// Должен вызываться до первого Go
+func (g *Group[TRequest, TResponse]) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+
+	g.sem = make(chan struct{}, n)
+}
+
+// Go - ставит задачу name в очередь. Go не блокируется, даже если достигнут лимит SetLimit
+func (g *Group[TRequest, TResponse]) Go(name string, params TRequest, f func(context.Context, TRequest) (TResponse, error)) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		if !g.acquire() {
+			g.skip(name)
+			return
+		}
+		defer g.release()
+
+		// контекст мог отмениться, пока задача ждала в очереди
+		if g.ctx.Err() != nil {
+			g.skip(name)
+			return
+		}
+
+		_, err := g.cb.Execute(g.ctx, params, f)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrCircuitOpened):
+			g.reject(name)
+			g.cancel(ErrCircuitOpened)
+		case errors.Is(err, context.Canceled) && errors.Is(context.Cause(g.ctx), ErrCircuitOpened):
+			g.mx.Lock()
+			g.cancelled = append(g.cancelled, name)
+			g.mx.Unlock()
+		default:
+			g.fail(err)
+		}
+	}()
+}
+
+// Wait - дожидается завершения всех задач и возвращает *GroupError, если хотя бы одна не выполнилась
+func (g *Group[TRequest, TResponse]) Wait() error {
+	g.wg.Wait()
+	g.unsubscribe()
+	g.cancel(context.Canceled)
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if g.err == nil && len(g.rejected) == 0 && len(g.cancelled) == 0 {
+		return nil
+	}
+
+	sort.Strings(g.rejected)
+	sort.Strings(g.cancelled)
+
+	return &GroupError{
+		Err:       g.err,
+		Rejected:  g.rejected,
+		Cancelled: g.cancelled,
+	}
+}
+
+func (g *Group[TRequest, TResponse]) acquire() bool {
+	if g.sem == nil {
+		return g.ctx.Err() == nil
+	}
+
+	select {
+	case <-g.ctx.Done():
+		return false
+	case g.sem <- struct{}{}:
+		return true
+	}
+}
+
+func (g *Group[TRequest, TResponse]) release() {
+	if g.sem != nil {
+		<-g.sem
+	}
+}
+
+func (g *Group[TRequest, TResponse]) reject(name string) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.rejected = append(g.rejected, name)
+}
+
+// skip - учитывает задачу, которая не запускалась из-за отмены контекста группы.
+// Отклоненной она считается, только если контекст отменило срабатывание предохранителя
+func (g *Group[TRequest, TResponse]) skip(name string) {
+	cause := context.Cause(g.ctx)
+	if errors.Is(cause, ErrCircuitOpened) {
+		g.reject(name)
+		return
+	}
+
+	g.fail(cause)
+}
+
+func (g *Group[TRequest, TResponse]) fail(err error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	if g.err == nil {
+		g.err = err
+	}
+}
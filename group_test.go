@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_Wait(t *testing.T) {
+	cb := NewCB[string, string](time.Second*3, time.Minute, 50, 1, 2)
+
+	g, ctx := NewGroup(context.Background(), cb)
+	g.SetLimit(2)
+
+	var (
+		started = make(chan string, 4)
+		release = make(chan struct{})
+		errFail = errors.New("fail")
+		calls   atomic.Int64
+	)
+
+	f := func(ctx context.Context, name string) (string, error) {
+		calls.Add(1)
+		started <- name
+
+		switch name {
+		case "slow":
+			<-ctx.Done()
+			return "", ctx.Err()
+		case "fail":
+			<-release
+			return "", errFail
+		}
+
+		return "ok", nil
+	}
+
+	g.Go("slow", "slow", f)
+	<-started
+	g.Go("fail", "fail", f)
+	<-started
+
+	// обе позиции лимита заняты - эти задачи ждут в очереди
+	g.Go("queued-1", "queued-1", f)
+	g.Go("queued-2", "queued-2", f)
+
+	close(release)
+
+	err := g.Wait()
+
+	var groupErr *GroupError
+	if !errors.As(err, &groupErr) {
+		t.Fatalf("Wanted *GroupError, but got %v", err)
+	}
+
+	if !errors.Is(err, errFail) || !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted error to wrap %s and %s, but got %s", errFail, ErrCircuitOpened, err)
+	}
+
+	if !slices.Equal(groupErr.Rejected, []string{"queued-1", "queued-2"}) {
+		t.Errorf("Got rejected tasks: %v", groupErr.Rejected)
+	}
+
+	if !slices.Equal(groupErr.Cancelled, []string{"slow"}) {
+		t.Errorf("Got cancelled tasks: %v", groupErr.Cancelled)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("Wanted 2 started tasks, but got %d", calls.Load())
+	}
+
+	if !errors.Is(context.Cause(ctx), ErrCircuitOpened) {
+		t.Errorf("Wanted group context cause %s, but got %v", ErrCircuitOpened, context.Cause(ctx))
+	}
+}
+
+func TestGroup_Wait_Success(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second*3, time.Minute, 50, 1, 2)
+
+	g, _ := NewGroup(context.Background(), cb)
+
+	for i := 0; i < 5; i++ {
+		g.Go("task", 0, F)
+	}
+
+	if err := g.Wait(); err != nil {
+		t.Errorf("Got error: %s", err.Error())
+	}
+}
+
+func TestGroup_Wait_Tripped_Outside(t *testing.T) {
+	cb := NewCB[string, string](time.Second*3, time.Minute, 50, 1, 1)
+
+	g, ctx := NewGroup(context.Background(), cb)
+	g.SetLimit(1)
+
+	started := make(chan struct{})
+
+	g.Go("slow", "slow", func(ctx context.Context, _ string) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	<-started
+
+	g.Go("queued", "queued", func(context.Context, string) (string, error) {
+		t.Errorf("Queued task was started after the breaker tripped")
+		return "", nil
+	})
+
+	// предохранитель срабатывает из-за запроса, который не относится к группе
+	_, _ = cb.Execute(context.Background(), "other", func(context.Context, string) (string, error) {
+		return "", errors.New("fail")
+	})
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Wanted group context to be cancelled when the breaker trips")
+	}
+
+	err := g.Wait()
+
+	var groupErr *GroupError
+	if !errors.As(err, &groupErr) {
+		t.Fatalf("Wanted *GroupError, but got %v", err)
+	}
+
+	if !slices.Equal(groupErr.Rejected, []string{"queued"}) || !slices.Equal(groupErr.Cancelled, []string{"slow"}) {
+		t.Errorf("Got rejected %v and cancelled %v", groupErr.Rejected, groupErr.Cancelled)
+	}
+}
+
+func TestGroup_Wait_Parent_Cancelled(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second*3, time.Minute, 50, 1, 2)
+
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g, _ := NewGroup(parent, cb)
+	g.Go("a", 0, F)
+
+	err := g.Wait()
+
+	var groupErr *GroupError
+	if !errors.As(err, &groupErr) {
+		t.Fatalf("Wanted *GroupError, but got %v", err)
+	}
+
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s without %s, but got %s", context.Canceled, ErrCircuitOpened, err)
+	}
+
+	if len(groupErr.Rejected) != 0 {
+		t.Errorf("Wanted no rejected tasks, but got %v", groupErr.Rejected)
+	}
+}
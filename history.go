@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHistoryResolution = errors.New("history resolution step must be positive and retention not less than step")
+)
+
+// latencyBuckets - количество корзин гистограммы задержек.
+// Границы корзин растут вдвое начиная с minLatencyBound: 1ms, 2ms, 4ms ... ~35min
+const latencyBuckets = 22
+
+const minLatencyBound = time.Millisecond
+
+// HistoryResolution - одна ступень истории: шаг агрегации и сколько времени хранить
+type HistoryResolution struct {
+	Step      time.Duration
+	Retention time.Duration
+}
+
+// DefaultHistoryResolutions - 10 секунд в течение часа, 1 минута в течение суток
+var DefaultHistoryResolutions = []HistoryResolution{
+	{Step: 10 * time.Second, Retention: time.Hour},
+	{Step: time.Minute, Retention: 24 * time.Hour},
+}
+
+// HistoryPoint - агрегированные метрики предохранителя за один шаг
+type HistoryPoint struct {
+	Time        time.Time     `json:"time"`
+	Calls       int64         `json:"calls"`
+	Failures    int64         `json:"failures"`
+	Rejected    int64         `json:"rejected"`
+	Fallbacks   int64         `json:"fallbacks"`
+	FailureRate float64       `json:"failure_rate"`
+	LatencyP50  time.Duration `json:"latency_p50"`
+	LatencyP90  time.Duration `json:"latency_p90"`
+	LatencyP99  time.Duration `json:"latency_p99"`
+	Status      Status        `json:"status"`
+	// Warning - в течение шага предохранитель был в статусе closed с предупреждением
+	Warning bool `json:"warning"`
+}
+
+type historyBucket struct {
+	start    time.Time
+	calls    int64
+	failures int64
+	rejected int64
+	// fallbacks - сколько из отклоненных запросов обслужил fallback
+	fallbacks int64
+	latency   [latencyBuckets]int64
+	status    Status
+	warning   bool
+}
+
+type historyTier struct {
+	step    time.Duration
+	buckets []historyBucket
+}
+
+// History - история метрик предохранителя в ограниченном объеме памяти.
+// Каждая ступень - кольцевой буфер из Retention/Step корзин
+type History struct {
+	mx    sync.Mutex
+	tiers []historyTier
+	now   func() time.Time
+}
+
+// NewHistory - история с заданными ступенями (по умолчанию DefaultHistoryResolutions).
+// Возвращает ErrHistoryResolution, если у ступени Step <= 0 или Retention < Step
+func NewHistory(resolutions ...HistoryResolution) (*History, error) {
+	if len(resolutions) == 0 {
+		resolutions = DefaultHistoryResolutions
+	}
+
+	tiers := make([]historyTier, 0, len(resolutions))
+	for _, res := range resolutions {
+		if res.Step <= 0 || res.Retention < res.Step {
+			return nil, ErrHistoryResolution
+		}
+
+		size := int(res.Retention / res.Step)
+
+		tiers = append(tiers, historyTier{
+			step:    res.Step,
+			buckets: make([]historyBucket, size),
+		})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].step < tiers[j].step
+	})
+
+	return &History{
+		tiers: tiers,
+		now:   time.Now,
+	}, nil
+}
+
+func (h *History) addCall(status Status, warning bool, latency time.Duration, success bool) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	now := h.now()
+	idx := latencyBucket(latency)
+
+	for i := range h.tiers {
+		b := h.tiers[i].bucket(now)
+		b.calls++
+		if !success {
+			b.failures++
+		}
+		b.latency[idx]++
+		b.status = status
+		b.warning = b.warning || warning
+	}
+}
+
+func (h *History) addRejection(status Status, fallback bool) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	now := h.now()
+
+	for i := range h.tiers {
+		b := h.tiers[i].bucket(now)
+		b.rejected++
+		if fallback {
+			b.fallbacks++
+		}
+		b.status = status
+	}
+}
+
+// Query - точки истории в диапазоне [from, to].
+// Используется самая подробная ступень, которая еще хранит момент from
+func (h *History) Query(from, to time.Time) []HistoryPoint {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if len(h.tiers) == 0 {
+		return nil
+	}
+
+	now := h.now()
+	tier := &h.tiers[len(h.tiers)-1]
+	for i := range h.tiers {
+		t := &h.tiers[i]
+		if now.Sub(from) <= t.step*time.Duration(len(t.buckets)) {
+			tier = t
+			break
+		}
+	}
+
+	points := make([]HistoryPoint, 0)
+	for _, b := range tier.buckets {
+		if b.start.IsZero() || b.start.Before(from.Truncate(tier.step)) || b.start.After(to) {
+			continue
+		}
+
+		// корзина из прошлого круга кольцевого буфера уже вышла за Retention
+		if now.Sub(b.start) >= tier.step*time.Duration(len(tier.buckets)) {
+			continue
+		}
+
+		points = append(points, b.point())
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return points
+}
+
+// QueryJSON - то же что Query, но в виде JSON
+func (h *History) QueryJSON(from, to time.Time) ([]byte, error) {
+	return json.Marshal(h.Query(from, to))
+}
+
+func (t *historyTier) bucket(now time.Time) *historyBucket {
+	start := now.Truncate(t.step)
+	b := &t.buckets[int(start.UnixNano()/int64(t.step))%len(t.buckets)]
+
+	if !b.start.Equal(start) {
+		*b = historyBucket{start: start}
+	}
+
+	return b
+}
+
+func (b *historyBucket) point() HistoryPoint {
+	p := HistoryPoint{
+		Time:       b.start,
+		Calls:      b.calls,
+		Failures:   b.failures,
+		Rejected:   b.rejected,
+		Fallbacks:  b.fallbacks,
+		LatencyP50: b.percentile(0.5),
+		LatencyP90: b.percentile(0.9),
+		LatencyP99: b.percentile(0.99),
+		Status:     b.status,
+		Warning:    b.warning,
+	}
+
+	if b.calls > 0 {
+		p.FailureRate = float64(b.failures) / float64(b.calls) * 100
+	}
+
+	return p
+}
+
+// percentile - верхняя граница корзины гистограммы, в которую попадает перцентиль q
+func (b *historyBucket) percentile(q float64) time.Duration {
+	if b.calls == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(b.calls))
+	if rank >= b.calls {
+		rank = b.calls - 1
+	}
+
+	var seen int64
+	for i, count := range b.latency {
+		seen += count
+		if seen > rank {
+			return minLatencyBound << i
+		}
+	}
+
+	return minLatencyBound << (latencyBuckets - 1)
+}
+
+func latencyBucket(latency time.Duration) int {
+	idx := 0
+	for bound := minLatencyBound; latency > bound && idx < latencyBuckets-1; bound <<= 1 {
+		idx++
+	}
+
+	return idx
+}
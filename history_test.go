@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistory_Query(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	h, err := NewHistory(
+		HistoryResolution{Step: 10 * time.Second, Retention: time.Minute},
+		HistoryResolution{Step: time.Minute, Retention: time.Hour},
+	)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	h.now = func() time.Time { return now }
+
+	h.addCall(StatusClosed, false, 3*time.Millisecond, true)
+	h.addCall(StatusClosed, false, 3*time.Millisecond, false)
+	h.addRejection(StatusOpen, false)
+
+	now = now.Add(10 * time.Second)
+	h.addCall(StatusHalfOpen, false, 100*time.Millisecond, true)
+
+	points := h.Query(now.Add(-time.Minute), now)
+	if len(points) != 2 {
+		t.Fatalf("Wanted 2 points, but got %d", len(points))
+	}
+
+	if points[0].Calls != 2 || points[0].Failures != 1 || points[0].Rejected != 1 {
+		t.Errorf("Got unexpected first point: %+v", points[0])
+	}
+
+	if points[0].FailureRate != 50 {
+		t.Errorf("Wanted failure rate 50, but got %v", points[0].FailureRate)
+	}
+
+	if points[0].LatencyP99 != 4*time.Millisecond {
+		t.Errorf("Wanted p99 4ms, but got %s", points[0].LatencyP99)
+	}
+
+	if points[1].Status != StatusHalfOpen {
+		t.Errorf("Wanted status %s, but got %s", StatusHalfOpen, points[1].Status)
+	}
+
+	// запрос за пределами 10-секундной ступени уходит на минутную
+	now = now.Add(5 * time.Minute)
+
+	points = h.Query(now.Add(-10*time.Minute), now)
+	if len(points) != 1 || points[0].Calls != 3 {
+		t.Errorf("Wanted 1 downsampled point with 3 calls, but got %+v", points)
+	}
+
+	data, err := h.QueryJSON(now.Add(-10*time.Minute), now)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	var decoded []map[string]any
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if decoded[0]["status"] != "half-open" {
+		t.Errorf("Wanted status half-open in JSON, but got %v", decoded[0]["status"])
+	}
+}
+
+func TestNewHistory_Invalid(t *testing.T) {
+	testCases := []struct {
+		name       string
+		resolution HistoryResolution
+	}{
+		{
+			name:       "Fail_Zero_Step",
+			resolution: HistoryResolution{Step: 0, Retention: time.Hour},
+		},
+		{
+			name:       "Fail_Negative_Retention",
+			resolution: HistoryResolution{Step: time.Second, Retention: -time.Hour},
+		},
+		{
+			name:       "Fail_Retention_Less_Than_Step",
+			resolution: HistoryResolution{Step: time.Minute, Retention: time.Second},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Running test case: %s", testCase.name)
+
+		if _, err := NewHistory(testCase.resolution); !errors.Is(err, ErrHistoryResolution) {
+			t.Errorf("Wanted %s, but got %v", ErrHistoryResolution, err)
+		}
+	}
+}
+
+func TestCircuitBreaker_History(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 2)
+
+	h, err := NewHistory()
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	cb.SetHistory(h)
+
+	ctx := context.Background()
+
+	if _, err := cb.Execute(ctx, 0, F); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	failing := func(context.Context, time.Duration) (string, error) {
+		return "", errors.New("fail")
+	}
+
+	if _, err := cb.Execute(ctx, 0, failing); err == nil {
+		t.Fatalf("Wanted error, but got nil")
+	}
+
+	if _, err := cb.Execute(ctx, 0, F); !errors.Is(err, ErrCircuitOpened) {
+		t.Fatalf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	points := h.Query(time.Now().Add(-time.Minute), time.Now())
+
+	var calls, failures, rejected int64
+	for _, p := range points {
+		calls += p.Calls
+		failures += p.Failures
+		rejected += p.Rejected
+	}
+
+	if calls != 2 || failures != 1 || rejected != 1 {
+		t.Errorf("Got calls=%d failures=%d rejected=%d", calls, failures, rejected)
+	}
+}
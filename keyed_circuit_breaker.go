@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// sketchDepth, sketchWidth - размеры count-min sketch для подсчета запросов по ключам
+	sketchDepth = 4
+	sketchWidth = 2048
+)
+
+// ErrKeyedDecayInterval - без затухания счетчиков отклоненный ключ не восстановится никогда:
+// отклоненные запросы не выполняются, поэтому счетчики по ключу больше не меняются
+var ErrKeyedDecayInterval = errors.New("keyed breaker decay interval must be positive")
+
+// KeyStats - оценка количества запросов и ошибок по ключу
+type KeyStats struct {
+	Key         string  `json:"key"`
+	Calls       int64   `json:"calls"`
+	Failures    int64   `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// KeyedCircuitBreaker - общий предохранитель для неограниченного множества ключей (id пользователей, URL и т.д.).
+// Вместо отдельного предохранителя на каждый ключ ошибки считаются в top-K структуре (space-saving),
+// а запросы - в count-min sketch, поэтому память не зависит от количества ключей.
+// Отклоняются только запросы по ключам, которые чаще всего падают
+type KeyedCircuitBreaker[TRequest, TResponse any] struct {
+	mx *sync.Mutex
+	// timeout - лимит обработки запроса
+	timeout time.Duration
+	// key - извлекает ключ из запроса
+	key func(TRequest) string
+	// errorThreshold - процент ошибок по ключу, начиная с которого запросы по нему отклоняются
+	errorThreshold float64
+	// minFailures - минимальное количество ошибок по ключу, чтобы начать отклонять запросы
+	minFailures int64
+	// decayInterval - раз в decayInterval все счетчики уменьшаются вдвое, чтобы ключи могли восстановиться
+	decayInterval time.Duration
+	lastDecay     time.Time
+
+	calls    *countMinSketch
+	failures *spaceSaving
+	now      func() time.Time
+}
+
+func NewKeyedCB[TRequest, TResponse any](timeout time.Duration, key func(TRequest) string, errorThreshold float64, minFailures int64, topK int, decayInterval time.Duration) (*KeyedCircuitBreaker[TRequest, TResponse], error) {
+	if decayInterval <= 0 {
+		return nil, ErrKeyedDecayInterval
+	}
+
+	return &KeyedCircuitBreaker[TRequest, TResponse]{
+		mx:             &sync.Mutex{},
+		timeout:        timeout,
+		key:            key,
+		errorThreshold: errorThreshold,
+		minFailures:    minFailures,
+		decayInterval:  decayInterval,
+		lastDecay:      time.Now(),
+		calls:          newCountMinSketch(),
+		failures:       newSpaceSaving(topK),
+		now:            time.Now,
+	}, nil
+}
+
+func (cb *KeyedCircuitBreaker[TRequest, TResponse]) Execute(ctx context.Context, params TRequest, f func(context.Context, TRequest) (TResponse, error)) (TResponse, error) {
+	key := cb.key(params)
+
+	cb.mx.Lock()
+	cb.decay()
+	if cb.rejects(key) {
+		cb.mx.Unlock()
+		return *new(TResponse), ErrCircuitOpened
+	}
+	cb.mx.Unlock()
+
+	result, err := call(ctx, cb.timeout, nil, params, f)
+
+	cb.mx.Lock()
+	cb.calls.add(key, 1)
+	if err != nil {
+		cb.failures.add(key)
+	}
+	cb.mx.Unlock()
+
+	if err != nil {
+		return *new(TResponse), err
+	}
+
+	return result, nil
+}
+
+// TopFailingKeys - до n ключей с наибольшим количеством ошибок
+func (cb *KeyedCircuitBreaker[TRequest, TResponse]) TopFailingKeys(n int) []KeyStats {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	cb.decay()
+
+	if n < 0 {
+		n = 0
+	}
+
+	top := cb.failures.top(n)
+
+	stats := make([]KeyStats, 0, len(top))
+	for _, entry := range top {
+		stats = append(stats, cb.stats(entry.key, entry.count-entry.overestimate))
+	}
+
+	return stats
+}
+
+func (cb *KeyedCircuitBreaker[TRequest, TResponse]) rejects(key string) bool {
+	// решение принимается по гарантированному минимуму ошибок, а не по оценке сверху:
+	// иначе ключ, вытеснивший другой, сразу получил бы чужие ошибки
+	failures := cb.failures.guaranteed(key)
+	if failures < cb.minFailures {
+		return false
+	}
+
+	return cb.stats(key, failures).FailureRate >= cb.errorThreshold
+}
+
+func (cb *KeyedCircuitBreaker[TRequest, TResponse]) stats(key string, failures int64) KeyStats {
+	calls := cb.calls.estimate(key)
+	// не даем проценту ошибок выйти за 100% из-за округления при затухании
+	if calls < failures {
+		calls = failures
+	}
+
+	stats := KeyStats{
+		Key:      key,
+		Calls:    calls,
+		Failures: failures,
+	}
+
+	if calls > 0 {
+		stats.FailureRate = float64(failures) / float64(calls) * 100
+	}
+
+	return stats
+}
+
+func (cb *KeyedCircuitBreaker[TRequest, TResponse]) decay() {
+	now := cb.now()
+	for now.Sub(cb.lastDecay) >= cb.decayInterval {
+		cb.calls.halve()
+		cb.failures.halve()
+		cb.lastDecay = cb.lastDecay.Add(cb.decayInterval)
+	}
+}
+
+type countMinSketch struct {
+	seeds    [sketchDepth]maphash.Seed
+	counters [sketchDepth][sketchWidth]int64
+}
+
+func newCountMinSketch() *countMinSketch {
+	s := &countMinSketch{}
+	for i := range s.seeds {
+		s.seeds[i] = maphash.MakeSeed()
+	}
+
+	return s
+}
+
+func (s *countMinSketch) add(key string, delta int64) {
+	for i := range s.seeds {
+		s.counters[i][maphash.String(s.seeds[i], key)%sketchWidth] += delta
+	}
+}
+
+func (s *countMinSketch) estimate(key string) int64 {
+	var estimate int64 = -1
+	for i := range s.seeds {
+		c := s.counters[i][maphash.String(s.seeds[i], key)%sketchWidth]
+		if estimate < 0 || c < estimate {
+			estimate = c
+		}
+	}
+
+	return estimate
+}
+
+func (s *countMinSketch) halve() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] /= 2
+		}
+	}
+}
+
+type spaceSavingEntry struct {
+	key   string
+	count int64
+	// overestimate - значение, унаследованное от вытесненного ключа: настоящее количество
+	// не меньше count - overestimate
+	overestimate int64
+}
+
+// spaceSaving - алгоритм Space-Saving: хранит не больше capacity ключей,
+// новый ключ вытесняет ключ с минимальным счетчиком и наследует его значение
+type spaceSaving struct {
+	capacity int
+	entries  map[string]*spaceSavingEntry
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{
+		capacity: capacity,
+		entries:  make(map[string]*spaceSavingEntry, capacity),
+	}
+}
+
+func (s *spaceSaving) add(key string) {
+	if s.capacity <= 0 {
+		return
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		entry.count++
+		return
+	}
+
+	if len(s.entries) < s.capacity {
+		s.entries[key] = &spaceSavingEntry{key: key, count: 1}
+		return
+	}
+
+	var evicted *spaceSavingEntry
+	for _, entry := range s.entries {
+		if evicted == nil || entry.count < evicted.count {
+			evicted = entry
+		}
+	}
+
+	delete(s.entries, evicted.key)
+	s.entries[key] = &spaceSavingEntry{key: key, count: evicted.count + 1, overestimate: evicted.count}
+}
+
+// count - оценка сверху количества ошибок по ключу
+func (s *spaceSaving) count(key string) int64 {
+	if entry, ok := s.entries[key]; ok {
+		return entry.count
+	}
+
+	return 0
+}
+
+// guaranteed - гарантированный минимум ошибок по ключу
+func (s *spaceSaving) guaranteed(key string) int64 {
+	if entry, ok := s.entries[key]; ok {
+		return entry.count - entry.overestimate
+	}
+
+	return 0
+}
+
+func (s *spaceSaving) top(n int) []spaceSavingEntry {
+	entries := make([]spaceSavingEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+
+		return entries[i].key < entries[j].key
+	})
+
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+
+	return entries
+}
+
+func (s *spaceSaving) halve() {
+	for k, entry := range s.entries {
+		if entry.count/2 == 0 {
+			delete(s.entries, k)
+			continue
+		}
+
+		entry.count /= 2
+		entry.overestimate /= 2
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKeyedCircuitBreaker_Execute(t *testing.T) {
+	now := time.Now()
+
+	cb, err := NewKeyedCB[string, string](time.Second, func(key string) string { return key }, 50, 3, 8, time.Minute)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	cb.now = func() time.Time { return now }
+	cb.lastDecay = now
+
+	ctx := context.Background()
+
+	f := func(_ context.Context, key string) (string, error) {
+		if key == "bad" {
+			return "", errors.New("fail")
+		}
+
+		return "ok", nil
+	}
+
+	// много разных здоровых ключей не должны вытеснять и не должны отклоняться
+	for i := 0; i < 1000; i++ {
+		if _, err := cb.Execute(ctx, fmt.Sprintf("user-%d", i), f); err != nil {
+			t.Fatalf("Got error: %s", err.Error())
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cb.Execute(ctx, "bad", f); errors.Is(err, ErrCircuitOpened) {
+			t.Fatalf("Key rejected after %d failures", i)
+		}
+	}
+
+	if _, err := cb.Execute(ctx, "bad", f); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	if _, err := cb.Execute(ctx, "user-1", f); err != nil {
+		t.Errorf("Got error: %s", err.Error())
+	}
+
+	top := cb.TopFailingKeys(5)
+	if len(top) != 1 || top[0].Key != "bad" || top[0].Failures != 3 {
+		t.Errorf("Got unexpected top failing keys: %+v", top)
+	}
+
+	if top = cb.TopFailingKeys(-1); len(top) != 0 {
+		t.Errorf("Wanted no keys for negative n, but got %+v", top)
+	}
+
+	// после затухания счетчиков ключ снова пропускается
+	now = now.Add(2 * time.Minute)
+
+	if _, err := cb.Execute(ctx, "bad", f); errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted key to recover after decay")
+	}
+}
+
+func TestKeyedCircuitBreaker_Execute_Evicting_Key(t *testing.T) {
+	cb, err := NewKeyedCB[string, string](time.Second, func(key string) string { return key }, 50, 3, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	failing := func(context.Context, string) (string, error) {
+		return "", errors.New("fail")
+	}
+
+	success := func(context.Context, string) (string, error) {
+		return "ok", nil
+	}
+
+	// top-K заполнен ключами, которые падают часто
+	for _, key := range []string{"bad-1", "bad-2"} {
+		for i := 0; i < 5; i++ {
+			_, _ = cb.Execute(ctx, key, failing)
+		}
+	}
+
+	// новый ключ упал один раз и вытеснил один из них, унаследовав его счетчик
+	if _, err := cb.Execute(ctx, "new", failing); errors.Is(err, ErrCircuitOpened) {
+		t.Fatalf("Wanted first call of new key to go through")
+	}
+
+	if _, err := cb.Execute(ctx, "new", success); err != nil {
+		t.Errorf("Wanted key with a single failure not to be rejected, but got %v", err)
+	}
+
+	for _, stats := range cb.TopFailingKeys(2) {
+		if stats.Key == "new" && stats.Failures != 1 {
+			t.Errorf("Wanted 1 guaranteed failure for new key, but got %d", stats.Failures)
+		}
+	}
+}
+
+func TestNewKeyedCB_Invalid(t *testing.T) {
+	for _, decayInterval := range []time.Duration{0, -time.Minute} {
+		if _, err := NewKeyedCB[string, string](time.Second, func(key string) string { return key }, 50, 3, 8, decayInterval); !errors.Is(err, ErrKeyedDecayInterval) {
+			t.Errorf("Wanted %s for decay interval %s, but got %v", ErrKeyedDecayInterval, decayInterval, err)
+		}
+	}
+}
+
+func TestSpaceSaving_Capacity(t *testing.T) {
+	s := newSpaceSaving(2)
+
+	s.add("a")
+	s.add("a")
+	s.add("a")
+	s.add("b")
+	s.add("c")
+
+	if len(s.entries) != 2 {
+		t.Fatalf("Wanted 2 entries, but got %d", len(s.entries))
+	}
+
+	if s.count("a") != 3 || s.count("c") != 2 || s.count("b") != 0 {
+		t.Errorf("Got a=%d b=%d c=%d", s.count("a"), s.count("b"), s.count("c"))
+	}
+
+	if s.guaranteed("c") != 1 {
+		t.Errorf("Wanted 1 guaranteed for c, but got %d", s.guaranteed("c"))
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrOverrideInvalid  = errors.New("override requires owner, reason and positive ttl")
+	ErrOverrideConflict = errors.New("circuit status is overridden by another owner")
+)
+
+// Override - ручное переопределение статуса предохранителя
+type Override struct {
+	Owner     string    `json:"owner"`
+	Reason    string    `json:"reason"`
+	Status    Status    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type override struct {
+	Override
+	timer *time.Timer
+}
+
+// ForceOpen - переводит предохранитель в статус opened на ttl.
+// Ручной переход не учитывается как срабатывание в Trips и в отчетах о надежности.
+// Переопределение другого владельца можно заменить только с force
+func (cb *CircuitBreaker[TRequest, TResponse]) ForceOpen(owner, reason string, ttl time.Duration, force bool) error {
+	return cb.setOverride(StatusOpen, owner, reason, ttl, force)
+}
+
+// ForceClosed - переводит предохранитель в статус closed на ttl, ошибки в это время его не откроют.
+// Переопределение другого владельца можно заменить только с force
+func (cb *CircuitBreaker[TRequest, TResponse]) ForceClosed(owner, reason string, ttl time.Duration, force bool) error {
+	return cb.setOverride(StatusClosed, owner, reason, ttl, force)
+}
+
+// ClearOverride - досрочно снимает переопределение. Переопределение другого владельца снимается только с force
+func (cb *CircuitBreaker[TRequest, TResponse]) ClearOverride(owner string, force bool) error {
+	cb.mx.Lock()
+
+	o := cb.override
+	if o == nil {
+		cb.mx.Unlock()
+		return nil
+	}
+
+	if o.Owner != owner && !force {
+		cb.mx.Unlock()
+		return ErrOverrideConflict
+	}
+
+	o.timer.Stop()
+	cb.override = nil
+	cb.mx.Unlock()
+
+	cb.resume(o.Status)
+
+	return nil
+}
+
+// Override - действующее ручное переопределение
+func (cb *CircuitBreaker[TRequest, TResponse]) Override() (Override, bool) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	if cb.override == nil {
+		return Override{}, false
+	}
+
+	return cb.override.Override, true
+}
+
+// SetOnOverrideExpired - fn вызывается после того, как переопределение истекло и предохранитель вернулся к автоматическому режиму
+func (cb *CircuitBreaker[TRequest, TResponse]) SetOnOverrideExpired(fn func(Override)) {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	cb.onOverrideExpired = fn
+}
+
+func (cb *CircuitBreaker[TRequest, TResponse]) setOverride(status Status, owner, reason string, ttl time.Duration, force bool) error {
+	if owner == "" || reason == "" || ttl <= 0 {
+		return ErrOverrideInvalid
+	}
+
+	cb.mx.Lock()
+
+	if cb.override != nil {
+		if cb.override.Owner != owner && !force {
+			cb.mx.Unlock()
+			return ErrOverrideConflict
+		}
+
+		cb.override.timer.Stop()
+	}
+
+	now := time.Now()
+	o := &override{
+		Override: Override{
+			Owner:     owner,
+			Reason:    reason,
+			Status:    status,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		},
+	}
+	o.timer = time.AfterFunc(ttl, func() {
+		cb.expireOverride(o)
+	})
+
+	cb.override = o
+	cb.mx.Unlock()
+
+	cb.transition(status, true)
+
+	return nil
+}
+
+func (cb *CircuitBreaker[TRequest, TResponse]) expireOverride(o *override) {
+	cb.mx.Lock()
+
+	// переопределение уже сняли или заменили
+	if cb.override != o {
+		cb.mx.Unlock()
+		return
+	}
+
+	cb.override = nil
+	fn := cb.onOverrideExpired
+	cb.mx.Unlock()
+
+	cb.resume(o.Status)
+
+	if fn != nil {
+		fn(o.Override)
+	}
+}
+
+// resume - возврат к автоматическому режиму после переопределения.
+// После принудительного opened предохранитель проверяет зависимость в статусе halfOpen
+func (cb *CircuitBreaker[TRequest, TResponse]) resume(forced Status) {
+	if forced == StatusOpen {
+		cb.transition(StatusHalfOpen, true)
+		return
+	}
+
+	cb.transition(StatusClosed, true)
+}
+
+func (cb *CircuitBreaker[TRequest, TResponse]) overridden() bool {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	return cb.override != nil
+}
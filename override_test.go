@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_ForceOpen(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+
+	expired := make(chan Override, 1)
+	cb.SetOnOverrideExpired(func(o Override) {
+		expired <- o
+	})
+
+	ctx := context.Background()
+
+	testCases := []struct {
+		name    string
+		apply   func() error
+		wantErr error
+	}{
+		{
+			name:    "Fail_Without_TTL",
+			apply:   func() error { return cb.ForceOpen("alice", "maintenance", 0, false) },
+			wantErr: ErrOverrideInvalid,
+		},
+		{
+			name:    "Fail_Without_Reason",
+			apply:   func() error { return cb.ForceOpen("alice", "", time.Minute, false) },
+			wantErr: ErrOverrideInvalid,
+		},
+		{
+			name:  "Success",
+			apply: func() error { return cb.ForceOpen("alice", "maintenance", time.Minute, false) },
+		},
+		{
+			name:    "Fail_Conflict",
+			apply:   func() error { return cb.ForceClosed("bob", "incident", time.Minute, false) },
+			wantErr: ErrOverrideConflict,
+		},
+		{
+			name:    "Fail_Clear_Conflict",
+			apply:   func() error { return cb.ClearOverride("bob", false) },
+			wantErr: ErrOverrideConflict,
+		},
+		{
+			name:  "Success_Same_Owner",
+			apply: func() error { return cb.ForceOpen("alice", "maintenance", 100*time.Millisecond, false) },
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Running test case: %s", testCase.name)
+
+		if err := testCase.apply(); !errors.Is(err, testCase.wantErr) {
+			t.Errorf("Wanted error %v, but got %v", testCase.wantErr, err)
+		}
+	}
+
+	o, ok := cb.Override()
+	if !ok || o.Owner != "alice" || o.Status != StatusOpen {
+		t.Fatalf("Got unexpected override: %+v, %v", o, ok)
+	}
+
+	if _, err := cb.Execute(ctx, 0, F); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	select {
+	case o = <-expired:
+		if o.Owner != "alice" {
+			t.Errorf("Got unexpected expired override: %+v", o)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Override did not expire")
+	}
+
+	if _, ok = cb.Override(); ok {
+		t.Errorf("Wanted no active override")
+	}
+
+	if cb.Status() != StatusHalfOpen {
+		t.Errorf("Wanted status %s, but got %s", StatusHalfOpen, cb.Status())
+	}
+}
+
+func TestCircuitBreaker_ForceClosed(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+
+	ctx := context.Background()
+
+	failing := func(context.Context, time.Duration) (string, error) {
+		return "", errors.New("fail")
+	}
+
+	if err := cb.ForceClosed("alice", "known flaky dependency", time.Minute, false); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cb.Execute(ctx, 0, failing); errors.Is(err, ErrCircuitOpened) {
+			t.Fatalf("Wanted calls to go through while forced closed")
+		}
+	}
+
+	if err := cb.ForceOpen("bob", "incident", time.Minute, true); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if o, _ := cb.Override(); o.Owner != "bob" {
+		t.Errorf("Wanted override by bob, but got %+v", o)
+	}
+
+	if err := cb.ClearOverride("bob", false); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if cb.Status() != StatusHalfOpen {
+		t.Errorf("Wanted status %s, but got %s", StatusHalfOpen, cb.Status())
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	ErrPoolExhausted = errors.New("pool max active connections reached")
+	ErrPoolClosed    = errors.New("pool closed")
+)
+
+// Pool - пул соединений, работающий вместе с предохранителем.
+// Новые соединения устанавливаются через предохранитель, а ошибки использования соединений
+// (Put с ошибкой, проваленная проверка при выдаче) учитываются им как неудачные запросы.
+// Пока предохранитель в статусе opened, пул пуст и сразу отказывает в выдаче соединений.
+// В статусе halfOpen одновременно может быть открыто не больше halfOpenLimit соединений,
+// поэтому пул наполняется постепенно
+type Pool[C io.Closer] struct {
+	cb *CircuitBreaker[struct{}, C]
+	// dial - открывает новое соединение. Должна учитывать ctx: соединение, открытое после таймаута предохранителя, теряется
+	dial func(context.Context) (C, error)
+	// healthCheck - проверка простаивавшего соединения перед выдачей (nil - без проверки)
+	healthCheck func(C) error
+	// maxIdle - сколько простаивающих соединений хранить
+	maxIdle int
+	// maxActive - сколько соединений может быть открыто одновременно (выданных и простаивающих)
+	maxActive int
+	// unsubscribe - отписка от смены статуса cb
+	unsubscribe func()
+
+	mx     sync.Mutex
+	idle   []C
+	active int
+	closed bool
+}
+
+func NewPool[C io.Closer](cb *CircuitBreaker[struct{}, C], dial func(context.Context) (C, error), healthCheck func(C) error, maxIdle, maxActive int) *Pool[C] {
+	p := &Pool[C]{
+		cb:          cb,
+		dial:        dial,
+		healthCheck: healthCheck,
+		maxIdle:     maxIdle,
+		maxActive:   maxActive,
+	}
+
+	// предохранитель может сработать не из-за пула, а из-за запросов в обход него
+	p.unsubscribe = cb.onTransition(func(_, to Status) {
+		if to == StatusOpen {
+			p.mx.Lock()
+			p.drain()
+			p.mx.Unlock()
+		}
+	})
+
+	return p
+}
+
+// Get - выдает простаивающее соединение или открывает новое
+func (p *Pool[C]) Get(ctx context.Context) (C, error) {
+	for {
+		p.mx.Lock()
+
+		if p.closed {
+			p.mx.Unlock()
+			return *new(C), ErrPoolClosed
+		}
+
+		status := p.cb.Status()
+		if status == StatusOpen {
+			p.drain()
+			p.mx.Unlock()
+
+			return *new(C), ErrCircuitOpened
+		}
+
+		if n := len(p.idle); n > 0 {
+			conn := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.mx.Unlock()
+
+			if p.healthCheck == nil || p.healthCheck(conn) == nil {
+				return conn, nil
+			}
+
+			p.discard(conn)
+			p.report(false)
+
+			continue
+		}
+
+		limit := p.maxActive
+		if status == StatusHalfOpen && int(p.cb.halfOpenLimit) < limit {
+			limit = int(p.cb.halfOpenLimit)
+		}
+
+		if p.active >= limit {
+			p.mx.Unlock()
+			return *new(C), ErrPoolExhausted
+		}
+
+		p.active++
+		p.mx.Unlock()
+
+		conn, err := p.cb.Execute(ctx, struct{}{}, func(ctx context.Context, _ struct{}) (C, error) {
+			return p.dial(ctx)
+		})
+		if err != nil {
+			p.mx.Lock()
+			p.active--
+			p.mx.Unlock()
+
+			return *new(C), err
+		}
+
+		return conn, nil
+	}
+}
+
+// Put - возвращает соединение в пул. err - ошибка, с которой закончилось использование соединения:
+// такое соединение закрывается и учитывается предохранителем как неудачный запрос
+func (p *Pool[C]) Put(conn C, err error) {
+	p.report(err == nil)
+
+	if err != nil {
+		p.discard(conn)
+		return
+	}
+
+	p.mx.Lock()
+
+	if p.closed || p.cb.Status() == StatusOpen || len(p.idle) >= p.maxIdle {
+		p.mx.Unlock()
+		p.discard(conn)
+
+		return
+	}
+
+	p.idle = append(p.idle, conn)
+	p.mx.Unlock()
+}
+
+// Active - количество открытых соединений (выданных и простаивающих)
+func (p *Pool[C]) Active() int {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.active
+}
+
+// Close - закрывает простаивающие соединения. Выданные соединения закроются при Put
+func (p *Pool[C]) Close() {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.closed = true
+	p.drain()
+	p.unsubscribe()
+}
+
+// drain - закрывает все простаивающие соединения. Вызывается под p.mx
+func (p *Pool[C]) drain() {
+	for _, conn := range p.idle {
+		_ = conn.Close()
+	}
+
+	p.active -= len(p.idle)
+	p.idle = nil
+}
+
+func (p *Pool[C]) discard(conn C) {
+	_ = conn.Close()
+
+	p.mx.Lock()
+	p.active--
+	p.mx.Unlock()
+}
+
+// report - передает предохранителю исход использования соединения.
+// В статусе opened исходы не учитываются: предохранитель в этот момент запросы не пропускает
+func (p *Pool[C]) report(success bool) {
+	if p.cb.Status() == StatusOpen {
+		return
+	}
+
+	p.cb.handleResponse(success)
+}
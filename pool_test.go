@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	down  atomic.Bool
+	dials atomic.Int64
+
+	mx    sync.Mutex
+	conns []*fakeConn
+}
+
+type fakeConn struct {
+	closed atomic.Bool
+}
+
+func (c *fakeConn) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func (b *fakeBackend) dial(context.Context) (*fakeConn, error) {
+	b.dials.Add(1)
+
+	if b.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+
+	conn := &fakeConn{}
+
+	b.mx.Lock()
+	b.conns = append(b.conns, conn)
+	b.mx.Unlock()
+
+	return conn, nil
+}
+
+func (b *fakeBackend) healthCheck(*fakeConn) error {
+	if b.down.Load() {
+		return errors.New("connection reset by peer")
+	}
+
+	return nil
+}
+
+func (b *fakeBackend) openConns() int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	open := 0
+	for _, conn := range b.conns {
+		if !conn.closed.Load() {
+			open++
+		}
+	}
+
+	return open
+}
+
+func TestPool_Get(t *testing.T) {
+	backend := &fakeBackend{}
+	cb := NewCB[struct{}, *fakeConn](time.Second, 50*time.Millisecond, 50, 2, 2)
+	pool := NewPool(cb, backend.dial, backend.healthCheck, 2, 3)
+
+	ctx := context.Background()
+
+	conns := make([]*fakeConn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err.Error())
+		}
+
+		conns = append(conns, conn)
+	}
+
+	if _, err := pool.Get(ctx); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("Wanted %s, but got %v", ErrPoolExhausted, err)
+	}
+
+	for _, conn := range conns {
+		pool.Put(conn, nil)
+	}
+
+	// третье соединение не помещается в maxIdle и закрывается
+	if pool.Active() != 2 || backend.openConns() != 2 {
+		t.Errorf("Wanted 2 idle connections, but got active=%d open=%d", pool.Active(), backend.openConns())
+	}
+
+	backend.down.Store(true)
+
+	// простаивающие соединения не проходят проверку, предохранитель срабатывает и пул опустошается
+	if _, err := pool.Get(ctx); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	if pool.Active() != 0 || backend.openConns() != 0 {
+		t.Errorf("Wanted drained pool, but got active=%d open=%d", pool.Active(), backend.openConns())
+	}
+
+	dials := backend.dials.Load()
+	if _, err := pool.Get(ctx); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	if backend.dials.Load() != dials {
+		t.Errorf("Wanted no dials while opened")
+	}
+
+	backend.down.Store(false)
+	time.Sleep(100 * time.Millisecond)
+
+	if cb.Status() != StatusHalfOpen {
+		t.Fatalf("Wanted status %s, but got %s", StatusHalfOpen, cb.Status())
+	}
+
+	conn, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	pool.Put(conn, nil)
+
+	if cb.Status() != StatusClosed {
+		t.Errorf("Wanted status %s, but got %s", StatusClosed, cb.Status())
+	}
+}
+
+func TestPool_Get_HalfOpenLimit(t *testing.T) {
+	backend := &fakeBackend{}
+	cb := NewCB[struct{}, *fakeConn](time.Second, time.Minute, 50, 2, 2)
+	pool := NewPool(cb, backend.dial, backend.healthCheck, 2, 10)
+
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Get(ctx); err != nil {
+			t.Fatalf("Got error: %s", err.Error())
+		}
+	}
+
+	cb.setStatus(StatusHalfOpen)
+
+	// в статусе halfOpen открыто не больше halfOpenLimit соединений
+	if _, err := pool.Get(ctx); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("Wanted %s, but got %v", ErrPoolExhausted, err)
+	}
+}
+
+func TestPool_Drain_Breaker_Tripped_Outside(t *testing.T) {
+	backend := &fakeBackend{}
+	cb := NewCB[struct{}, *fakeConn](time.Second, time.Minute, 50, 1, 1)
+	pool := NewPool(cb, backend.dial, backend.healthCheck, 2, 2)
+
+	ctx := context.Background()
+
+	conns := make([]*fakeConn, 0, 2)
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatalf("Got error: %s", err.Error())
+		}
+
+		conns = append(conns, conn)
+	}
+
+	for _, conn := range conns {
+		pool.Put(conn, nil)
+	}
+
+	if backend.openConns() != 2 {
+		t.Fatalf("Wanted 2 idle connections, but got %d", backend.openConns())
+	}
+
+	// предохранитель срабатывает из-за запроса, который не проходит через пул
+	_, _ = cb.Execute(ctx, struct{}{}, func(context.Context, struct{}) (*fakeConn, error) {
+		return nil, errors.New("fail")
+	})
+
+	if backend.openConns() != 0 || pool.Active() != 0 {
+		t.Errorf("Wanted idle connections to be closed, but got %d open and %d active", backend.openConns(), pool.Active())
+	}
+}
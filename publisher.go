@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSpoolFull = errors.New("publisher spool is full")
+)
+
+// Publisher - отправка сообщений в брокер
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msg []byte) error
+}
+
+// Message - сообщение, ожидающее отправки
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// SpoolingPublisher - Publisher, который отправляет сообщения через предохранитель.
+// Пока предохранитель в статусе opened, сообщения складываются в ограниченную очередь в памяти
+// и после восстановления отправляются в том же порядке.
+//
+// Гарантии доставки:
+//   - если Publish вернул ошибку, сообщение не было поставлено в очередь, решение о повторе за вызывающим;
+//   - если Publish вернул nil, сообщение либо отправлено, либо лежит в очереди;
+//   - сообщения из очереди отправляются по порядку, не обгоняя друг друга и новые сообщения,
+//     и доставляются хотя бы один раз: при таймауте сообщение отправляется повторно, поэтому возможны дубли;
+//   - очередь хранится только в памяти процесса и теряется при его остановке
+type SpoolingPublisher struct {
+	publisher Publisher
+	cb        *CircuitBreaker[Message, struct{}]
+	// limit - максимальное количество сообщений в очереди
+	limit int
+
+	mx    sync.Mutex
+	spool []Message
+	// draining - очередь сейчас отправляется, новые сообщения должны вставать за ней
+	draining bool
+	kick     chan struct{}
+}
+
+func NewSpoolingPublisher(publisher Publisher, cb *CircuitBreaker[Message, struct{}], limit int) *SpoolingPublisher {
+	return &SpoolingPublisher{
+		publisher: publisher,
+		cb:        cb,
+		limit:     limit,
+		kick:      make(chan struct{}, 1),
+	}
+}
+
+func (p *SpoolingPublisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	message := Message{Topic: topic, Payload: msg}
+
+	p.mx.Lock()
+	if len(p.spool) > 0 || p.draining {
+		defer p.mx.Unlock()
+
+		// в очереди уже есть сообщения - отправка напрямую нарушила бы порядок
+		return p.enqueue(message)
+	}
+	p.mx.Unlock()
+
+	_, err := p.cb.Execute(ctx, message, p.send)
+	if !errors.Is(err, ErrCircuitOpened) {
+		return err
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.enqueue(message)
+}
+
+// Spooled - количество сообщений в очереди
+func (p *SpoolingPublisher) Spooled() int {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return len(p.spool)
+}
+
+// Run - отправляет очередь после восстановления предохранителя, пока ctx не отменен.
+// Попытка делается раз в interval и сразу после постановки сообщения в очередь
+func (p *SpoolingPublisher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.kick:
+		}
+
+		_ = p.Drain(ctx)
+	}
+}
+
+// Drain - отправляет сообщения из очереди по порядку, пока очередь не опустеет или отправка не упадет
+func (p *SpoolingPublisher) Drain(ctx context.Context) error {
+	p.mx.Lock()
+	if p.draining {
+		p.mx.Unlock()
+		return nil
+	}
+	p.draining = true
+	p.mx.Unlock()
+
+	defer func() {
+		p.mx.Lock()
+		p.draining = false
+		p.mx.Unlock()
+	}()
+
+	for {
+		p.mx.Lock()
+		if len(p.spool) == 0 {
+			p.mx.Unlock()
+			return nil
+		}
+		message := p.spool[0]
+		p.mx.Unlock()
+
+		if p.cb.Status() == StatusOpen {
+			return ErrCircuitOpened
+		}
+
+		if _, err := p.cb.Execute(ctx, message, p.send); err != nil {
+			return err
+		}
+
+		p.mx.Lock()
+		p.spool = p.spool[1:]
+		p.mx.Unlock()
+	}
+}
+
+func (p *SpoolingPublisher) send(ctx context.Context, message Message) (struct{}, error) {
+	return struct{}{}, p.publisher.Publish(ctx, message.Topic, message.Payload)
+}
+
+func (p *SpoolingPublisher) enqueue(message Message) error {
+	if len(p.spool) >= p.limit {
+		return ErrSpoolFull
+	}
+
+	p.spool = append(p.spool, message)
+
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
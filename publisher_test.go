@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryBroker struct {
+	mx       sync.Mutex
+	down     bool
+	messages []string
+}
+
+func (b *memoryBroker) Publish(_ context.Context, topic string, msg []byte) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.down {
+		return errors.New("broker is unreachable")
+	}
+
+	b.messages = append(b.messages, topic+":"+string(msg))
+
+	return nil
+}
+
+func (b *memoryBroker) setDown(down bool) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.down = down
+}
+
+func (b *memoryBroker) published() []string {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return slices.Clone(b.messages)
+}
+
+func TestSpoolingPublisher_Publish(t *testing.T) {
+	broker := &memoryBroker{}
+	cb := NewCB[Message, struct{}](time.Second, 50*time.Millisecond, 50, 1, 2)
+	p := NewSpoolingPublisher(broker, cb, 2)
+
+	ctx := context.Background()
+
+	testCases := []struct {
+		name    string
+		msg     string
+		down    bool
+		wantErr error
+	}{
+		{
+			name: "Success",
+			msg:  "1",
+		},
+		{
+			name:    "Fail_Broker_Down",
+			msg:     "2",
+			down:    true,
+			wantErr: errors.New("broker is unreachable"),
+		},
+		{
+			name: "Success_Spooled",
+			msg:  "3",
+			down: true,
+		},
+		{
+			name: "Success_Spooled",
+			msg:  "4",
+		},
+		{
+			name:    "Fail_Spool_Full",
+			msg:     "5",
+			wantErr: ErrSpoolFull,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Running test case: %s", testCase.name)
+
+		broker.setDown(testCase.down)
+
+		err := p.Publish(ctx, "events", []byte(testCase.msg))
+		if testCase.wantErr == nil && err != nil {
+			t.Errorf("Got error: %s", err.Error())
+			continue
+		}
+
+		if testCase.wantErr != nil && (err == nil || err.Error() != testCase.wantErr.Error()) {
+			t.Errorf("Wanted error %s, but got %v", testCase.wantErr, err)
+		}
+	}
+
+	if p.Spooled() != 2 {
+		t.Fatalf("Wanted 2 spooled messages, but got %d", p.Spooled())
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go p.Run(runCtx, 10*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for p.Spooled() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := p.Publish(ctx, "events", []byte("6")); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	want := []string{"events:1", "events:3", "events:4", "events:6"}
+	if got := broker.published(); !slices.Equal(got, want) {
+		t.Errorf("Wanted %v, but got %v", want, got)
+	}
+}
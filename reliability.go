@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReliabilityReport - показатели надежности зависимости за период наблюдения
+type ReliabilityReport struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Availability - доля времени (в процентах), которую предохранитель провел в статусе closed
+	Availability float64       `json:"availability"`
+	TimeClosed   time.Duration `json:"time_closed"`
+	TimeOpen     time.Duration `json:"time_open"`
+	TimeHalfOpen time.Duration `json:"time_half_open"`
+	// Trips - количество переходов closed -> opened, кроме переходов по ручному переопределению
+	Trips int64 `json:"trips"`
+	// MTTR - среднее время от срабатывания до возврата в closed
+	MTTR time.Duration `json:"mttr"`
+	// MTBF - среднее время работы в closed между срабатываниями
+	MTBF     time.Duration `json:"mtbf"`
+	Requests int64         `json:"requests"`
+	Failures int64         `json:"failures"`
+	Rejected int64         `json:"rejected"`
+	// RejectedShare - доля отклоненных запросов (в процентах) от всех запросов
+	RejectedShare float64 `json:"rejected_share"`
+	Fallbacks     int64   `json:"fallbacks"`
+	// FallbackShare - доля запросов (в процентах) от всех запросов, которые обслужил fallback
+	FallbackShare float64 `json:"fallback_share"`
+}
+
+// ReliabilityRecorder - накапливает переходы статусов и исходы запросов предохранителя.
+// Хранит только агрегаты, поэтому память не растет со временем
+type ReliabilityRecorder struct {
+	mx  sync.Mutex
+	now func() time.Time
+
+	start time.Time
+	// status, since - текущий статус и время перехода в него
+	status Status
+	since  time.Time
+	// durations - накопленное время в каждом статусе (кроме текущего отрезка)
+	durations map[Status]time.Duration
+
+	trips int64
+	// tripStart - время последнего срабатывания из closed, если предохранитель еще не восстановился
+	tripStart    time.Time
+	recoveries   int64
+	recoveryTime time.Duration
+
+	requests  int64
+	failures  int64
+	rejected  int64
+	fallbacks int64
+}
+
+func NewReliabilityRecorder() *ReliabilityRecorder {
+	return newReliabilityRecorder(time.Now)
+}
+
+func newReliabilityRecorder(now func() time.Time) *ReliabilityRecorder {
+	start := now()
+
+	return &ReliabilityRecorder{
+		now:       now,
+		start:     start,
+		status:    StatusClosed,
+		since:     start,
+		durations: make(map[Status]time.Duration),
+	}
+}
+
+// Report - отчет с момента создания recorder'а до текущего момента
+func (r *ReliabilityRecorder) Report() ReliabilityReport {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	now := r.now()
+
+	durations := make(map[Status]time.Duration, len(r.durations)+1)
+	for status, d := range r.durations {
+		durations[status] = d
+	}
+	durations[r.status] += now.Sub(r.since)
+
+	report := ReliabilityReport{
+		From:         r.start,
+		To:           now,
+		TimeClosed:   durations[StatusClosed],
+		TimeOpen:     durations[StatusOpen],
+		TimeHalfOpen: durations[StatusHalfOpen],
+		Trips:        r.trips,
+		Requests:     r.requests,
+		Failures:     r.failures,
+		Rejected:     r.rejected,
+		Fallbacks:    r.fallbacks,
+	}
+
+	if period := now.Sub(r.start); period > 0 {
+		report.Availability = float64(report.TimeClosed) / float64(period) * 100
+	}
+
+	if r.recoveries > 0 {
+		report.MTTR = r.recoveryTime / time.Duration(r.recoveries)
+	}
+
+	if r.trips > 0 {
+		report.MTBF = report.TimeClosed / time.Duration(r.trips)
+	}
+
+	if r.requests > 0 {
+		report.RejectedShare = float64(r.rejected) / float64(r.requests) * 100
+		report.FallbackShare = float64(r.fallbacks) / float64(r.requests) * 100
+	}
+
+	return report
+}
+
+func (r ReliabilityReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r ReliabilityReport) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "| Metric | Value |\n")
+	fmt.Fprintf(&b, "| --- | --- |\n")
+	fmt.Fprintf(&b, "| Period | %s - %s |\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	fmt.Fprintf(&b, "| Availability | %.2f%% |\n", r.Availability)
+	fmt.Fprintf(&b, "| Time closed | %s |\n", r.TimeClosed)
+	fmt.Fprintf(&b, "| Time open | %s |\n", r.TimeOpen)
+	fmt.Fprintf(&b, "| Time half-open | %s |\n", r.TimeHalfOpen)
+	fmt.Fprintf(&b, "| Trips | %d |\n", r.Trips)
+	fmt.Fprintf(&b, "| MTTR | %s |\n", r.MTTR)
+	fmt.Fprintf(&b, "| MTBF | %s |\n", r.MTBF)
+	fmt.Fprintf(&b, "| Requests | %d |\n", r.Requests)
+	fmt.Fprintf(&b, "| Failures | %d |\n", r.Failures)
+	fmt.Fprintf(&b, "| Rejected | %d (%.2f%%) |\n", r.Rejected, r.RejectedShare)
+	fmt.Fprintf(&b, "| Served by fallback | %d (%.2f%%) |\n", r.Fallbacks, r.FallbackShare)
+
+	return b.String()
+}
+
+// attach - синхронизирует статус recorder'а со статусом предохранителя при подключении
+func (r *ReliabilityRecorder) attach(status Status) {
+	r.mx.Lock()
+	current := r.status
+	r.mx.Unlock()
+
+	if current != status {
+		r.addTransition(current, status, false)
+	}
+}
+
+// addTransition - учитывает смену статуса. Ручные переходы (manual) меняют только время в статусах:
+// они не считаются срабатыванием, а срабатывание, прерванное вручную, - восстановлением
+func (r *ReliabilityRecorder) addTransition(from, to Status, manual bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	now := r.now()
+
+	r.durations[r.status] += now.Sub(r.since)
+	r.status = to
+	r.since = now
+
+	switch {
+	case manual:
+		r.tripStart = time.Time{}
+	case from == StatusClosed && to == StatusOpen:
+		r.trips++
+		r.tripStart = now
+	case to == StatusClosed && !r.tripStart.IsZero():
+		r.recoveries++
+		r.recoveryTime += now.Sub(r.tripStart)
+		r.tripStart = time.Time{}
+	}
+}
+
+func (r *ReliabilityRecorder) addCall(success bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.requests++
+	if !success {
+		r.failures++
+	}
+}
+
+func (r *ReliabilityRecorder) addRejection(fallback bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.requests++
+	r.rejected++
+	if fallback {
+		r.fallbacks++
+	}
+}
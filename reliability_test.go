@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReliabilityRecorder_Report(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newReliabilityRecorder(func() time.Time { return now })
+
+	// 10m closed -> 2m open -> 1m half-open -> 7m closed -> 5m open
+	now = now.Add(10 * time.Minute)
+	r.addTransition(StatusClosed, StatusOpen, false)
+	now = now.Add(2 * time.Minute)
+	r.addTransition(StatusOpen, StatusHalfOpen, false)
+	now = now.Add(time.Minute)
+	r.addTransition(StatusHalfOpen, StatusClosed, false)
+	now = now.Add(7 * time.Minute)
+	r.addTransition(StatusClosed, StatusOpen, false)
+	now = now.Add(5 * time.Minute)
+
+	r.addCall(true)
+	r.addCall(false)
+	r.addRejection(false)
+	r.addRejection(false)
+
+	report := r.Report()
+
+	if report.TimeClosed != 17*time.Minute || report.TimeOpen != 7*time.Minute || report.TimeHalfOpen != time.Minute {
+		t.Errorf("Got closed=%s open=%s half-open=%s", report.TimeClosed, report.TimeOpen, report.TimeHalfOpen)
+	}
+
+	if report.Trips != 2 {
+		t.Errorf("Wanted 2 trips, but got %d", report.Trips)
+	}
+
+	if report.MTTR != 3*time.Minute {
+		t.Errorf("Wanted MTTR 3m, but got %s", report.MTTR)
+	}
+
+	if report.MTBF != 8*time.Minute+30*time.Second {
+		t.Errorf("Wanted MTBF 8m30s, but got %s", report.MTBF)
+	}
+
+	if report.Availability != 68 {
+		t.Errorf("Wanted availability 68, but got %v", report.Availability)
+	}
+
+	if report.Requests != 4 || report.Failures != 1 || report.RejectedShare != 50 {
+		t.Errorf("Got unexpected outcome counters: %+v", report)
+	}
+
+	if _, err := report.JSON(); err != nil {
+		t.Errorf("Got error: %s", err.Error())
+	}
+
+	if md := report.Markdown(); !strings.Contains(md, "| MTTR | 3m0s |") {
+		t.Errorf("Got unexpected markdown:\n%s", md)
+	}
+}
+
+func TestCircuitBreaker_Reliability(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+
+	r := NewReliabilityRecorder()
+	cb.SetReliability(r)
+
+	ctx := context.Background()
+
+	failing := func(context.Context, time.Duration) (string, error) {
+		return "", errors.New("fail")
+	}
+
+	if _, err := cb.Execute(ctx, 0, failing); err == nil {
+		t.Fatalf("Wanted error, but got nil")
+	}
+
+	if _, err := cb.Execute(ctx, 0, F); !errors.Is(err, ErrCircuitOpened) {
+		t.Fatalf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	report := r.Report()
+	if report.Trips != 1 || report.Requests != 2 || report.Rejected != 1 {
+		t.Errorf("Got unexpected report: %+v", report)
+	}
+}
+
+func TestCircuitBreaker_Reliability_ForceOpen(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 1)
+
+	r := NewReliabilityRecorder()
+	cb.SetReliability(r)
+
+	if err := cb.ForceOpen("oncall", "maintenance", time.Minute, false); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if err := cb.ClearOverride("oncall", false); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	// после ручного opened предохранитель проверяет зависимость и закрывается
+	if _, err := cb.Execute(context.Background(), 0, F); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if cb.Status() != StatusClosed {
+		t.Fatalf("Wanted status %s, but got %s", StatusClosed, cb.Status())
+	}
+
+	if cb.Trips() != 0 {
+		t.Errorf("Wanted manual open not to count as trip, but got %d", cb.Trips())
+	}
+
+	report := r.Report()
+	if report.Trips != 0 || report.MTTR != 0 || report.MTBF != 0 {
+		t.Errorf("Wanted no trips and recoveries, but got %+v", report)
+	}
+}
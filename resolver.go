@@ -0,0 +1,234 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrResolverMaxEntries - без места хотя бы под один предохранитель и ответ Resolver ничего не защищает
+var ErrResolverMaxEntries = errors.New("resolver max entries must be positive")
+
+// ErrResolverFallback - предохранитель Resolver подменил ответ через fallback, а Resolver не знает, как его отдать.
+// Для ответов во время сбоя у Resolver есть свой кэш
+var ErrResolverFallback = errors.New("fallback answers are not supported by Resolver")
+
+// HostResolver - часть net.Resolver, которую использует Resolver
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+var _ HostResolver = (*net.Resolver)(nil)
+
+// ResolverStats - счетчики Resolver
+type ResolverStats struct {
+	Lookups int64 `json:"lookups"`
+	// Stale - ответы, отданные из кэша, пока предохранитель был в статусе opened
+	Stale int64 `json:"stale"`
+	// Rejected - запросы, отклоненные предохранителем, для которых в кэше не нашлось свежего ответа
+	Rejected int64 `json:"rejected"`
+}
+
+// Resolver - HostResolver, запросы которого выполняются через предохранители.
+// Предохранитель выбирается по key(host): host - отдельный предохранитель на каждое имя,
+// адрес DNS сервера - один предохранитель на сервер.
+// Пока предохранитель в статусе opened, отдаются последние удачные ответы из кэша, если они не старше maxStale.
+// Ответ "имя не найдено" - нормальный ответ DNS сервера и неудачей не считается
+type Resolver struct {
+	resolver HostResolver
+	key      func(host string) string
+	newCB    func() *CircuitBreaker[string, any]
+	// maxStale - максимальный возраст ответа из кэша
+	maxStale time.Duration
+	// maxEntries - максимальное количество ответов в кэше и предохранителей
+	maxEntries int
+
+	mx sync.Mutex
+	// breakers, breakerLRU - предохранители с вытеснением давно не используемых
+	breakers   map[string]*list.Element
+	breakerLRU *list.List
+	cache      map[string]*list.Element
+	lru        *list.List
+
+	lookups  atomic.Int64
+	stale    atomic.Int64
+	rejected atomic.Int64
+	now      func() time.Time
+}
+
+type cachedAnswer struct {
+	key    string
+	answer any
+	at     time.Time
+}
+
+type cachedBreaker struct {
+	key string
+	cb  *CircuitBreaker[string, any]
+}
+
+// dnsResult - ответ DNS сервера, включая "имя не найдено"
+type dnsResult struct {
+	answer any
+	err    error
+}
+
+func NewResolver(resolver HostResolver, key func(host string) string, newCB func() *CircuitBreaker[string, any], maxStale time.Duration, maxEntries int) (*Resolver, error) {
+	if maxEntries <= 0 {
+		return nil, ErrResolverMaxEntries
+	}
+
+	return &Resolver{
+		resolver:   resolver,
+		key:        key,
+		newCB:      newCB,
+		maxStale:   maxStale,
+		maxEntries: maxEntries,
+		breakers:   make(map[string]*list.Element),
+		breakerLRU: list.New(),
+		cache:      make(map[string]*list.Element),
+		lru:        list.New(),
+		now:        time.Now,
+	}, nil
+}
+
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return lookup(r, ctx, "host", host, func(ctx context.Context) ([]string, error) {
+		return r.resolver.LookupHost(ctx, host)
+	})
+}
+
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return lookup(r, ctx, "ip", host, func(ctx context.Context) ([]net.IPAddr, error) {
+		return r.resolver.LookupIPAddr(ctx, host)
+	})
+}
+
+func (r *Resolver) Stats() ResolverStats {
+	return ResolverStats{
+		Lookups:  r.lookups.Load(),
+		Stale:    r.stale.Load(),
+		Rejected: r.rejected.Load(),
+	}
+}
+
+// lookup - запрос через предохранитель. Ответ в кэше и ответы вызывающим - разные срезы,
+// как и у net.Resolver: изменение полученного ответа не меняет кэш
+func lookup[T ~[]E, E any](r *Resolver, ctx context.Context, kind, host string, fn func(context.Context) (T, error)) (T, error) {
+	r.lookups.Add(1)
+
+	cacheKey := kind + ":" + host
+
+	v, err := r.breaker(r.key(host)).Execute(ctx, host, func(ctx context.Context, _ string) (any, error) {
+		answer, err := fn(ctx)
+		if err != nil && !isNotFound(err) {
+			return nil, err
+		}
+
+		return dnsResult{answer: answer, err: err}, nil
+	})
+	if errors.Is(err, ErrCircuitOpened) {
+		if answer, ok := r.cached(cacheKey); ok {
+			r.stale.Add(1)
+
+			return slices.Clone(answer.(T)), nil
+		}
+
+		r.rejected.Add(1)
+
+		return *new(T), fmt.Errorf("lookup %s: %w", host, err)
+	}
+
+	if err != nil {
+		return *new(T), err
+	}
+
+	result, ok := v.(dnsResult)
+	if !ok {
+		return *new(T), fmt.Errorf("lookup %s: %w", host, ErrResolverFallback)
+	}
+
+	if result.err != nil {
+		return *new(T), result.err
+	}
+
+	answer := result.answer.(T)
+	r.store(cacheKey, slices.Clone(answer))
+
+	return answer, nil
+}
+
+func (r *Resolver) breaker(key string) *CircuitBreaker[string, any] {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if el, ok := r.breakers[key]; ok {
+		r.breakerLRU.MoveToFront(el)
+
+		return el.Value.(*cachedBreaker).cb
+	}
+
+	cb := r.newCB()
+	r.breakers[key] = r.breakerLRU.PushFront(&cachedBreaker{key: key, cb: cb})
+
+	// вытесняется и открытый предохранитель: иначе во время сбоя их количество не ограничено
+	for r.breakerLRU.Len() > r.maxEntries {
+		oldest := r.breakerLRU.Back()
+		r.breakerLRU.Remove(oldest)
+		delete(r.breakers, oldest.Value.(*cachedBreaker).key)
+	}
+
+	return cb
+}
+
+func (r *Resolver) cached(key string) (any, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	el, ok := r.cache[key]
+	if !ok {
+		return nil, false
+	}
+
+	answer := el.Value.(*cachedAnswer)
+	if r.now().Sub(answer.at) > r.maxStale {
+		return nil, false
+	}
+
+	r.lru.MoveToFront(el)
+
+	return answer.answer, true
+}
+
+func (r *Resolver) store(key string, answer any) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if el, ok := r.cache[key]; ok {
+		el.Value = &cachedAnswer{key: key, answer: answer, at: r.now()}
+		r.lru.MoveToFront(el)
+
+		return
+	}
+
+	r.cache[key] = r.lru.PushFront(&cachedAnswer{key: key, answer: answer, at: r.now()})
+
+	for r.lru.Len() > r.maxEntries {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.cache, oldest.Value.(*cachedAnswer).key)
+	}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeResolver struct {
+	down  atomic.Bool
+	hosts map[string][]string
+}
+
+func (f *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if f.down.Load() {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+	}
+
+	addrs, ok := f.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+func (f *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := f.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IPAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+
+	return ips, nil
+}
+
+func TestResolver_LookupHost(t *testing.T) {
+	fake := &fakeResolver{
+		hosts: map[string][]string{
+			"api.local": {"10.0.0.1"},
+			"db.local":  {"10.0.0.2"},
+		},
+	}
+
+	now := time.Now()
+
+	r, err := NewResolver(fake, func(host string) string { return host }, func() *CircuitBreaker[string, any] {
+		return NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+	}, time.Hour, 16)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+	r.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	if addrs, err := r.LookupHost(ctx, "api.local"); err != nil || !slices.Equal(addrs, []string{"10.0.0.1"}) {
+		t.Fatalf("Wanted 10.0.0.1, but got %v, %v", addrs, err)
+	}
+
+	// "имя не найдено" не открывает предохранитель
+	for i := 0; i < 2; i++ {
+		if _, err := r.LookupHost(ctx, "missing.local"); err == nil {
+			t.Fatalf("Wanted error, but got nil")
+		}
+	}
+
+	fake.down.Store(true)
+
+	var dnsErr *net.DNSError
+	if _, err := r.LookupHost(ctx, "missing.local"); !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+		t.Errorf("Wanted timeout from nameserver, but got %v", err)
+	}
+
+	if _, err := r.LookupHost(ctx, "api.local"); err == nil {
+		t.Fatalf("Wanted error, but got nil")
+	}
+
+	// предохранитель api.local открыт - отдается последний удачный ответ
+	addrs, err := r.LookupHost(ctx, "api.local")
+	if err != nil || !slices.Equal(addrs, []string{"10.0.0.1"}) {
+		t.Errorf("Wanted stale 10.0.0.1, but got %v, %v", addrs, err)
+	}
+
+	// для IP адресов своего ответа в кэше нет
+	if _, err = r.LookupIPAddr(ctx, "api.local"); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	// ответ старше maxStale не отдается
+	now = now.Add(2 * time.Hour)
+
+	if _, err = r.LookupHost(ctx, "api.local"); !errors.Is(err, ErrCircuitOpened) {
+		t.Errorf("Wanted %s, but got %v", ErrCircuitOpened, err)
+	}
+
+	want := ResolverStats{Lookups: 8, Stale: 1, Rejected: 2}
+	if stats := r.Stats(); stats != want {
+		t.Errorf("Wanted %+v, but got %+v", want, stats)
+	}
+}
+
+func TestResolver_Store_Bounded(t *testing.T) {
+	r, err := NewResolver(&fakeResolver{}, func(string) string { return "ns" }, func() *CircuitBreaker[string, any] {
+		return NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+	}, time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	r.store("host:a", []string{"1"})
+	r.store("host:b", []string{"2"})
+	r.store("host:c", []string{"3"})
+
+	if _, ok := r.cached("host:a"); ok {
+		t.Errorf("Wanted oldest answer to be evicted")
+	}
+
+	if _, ok := r.cached("host:c"); !ok {
+		t.Errorf("Wanted newest answer to be cached")
+	}
+}
+
+func TestResolver_Breakers_Bounded(t *testing.T) {
+	fake := &fakeResolver{}
+	fake.down.Store(true)
+
+	r, err := NewResolver(fake, func(host string) string { return host }, func() *CircuitBreaker[string, any] {
+		return NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+	}, time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	// во время сбоя все предохранители открыты, но их количество все равно ограничено
+	for _, host := range []string{"a.local", "b.local", "c.local", "d.local"} {
+		if _, err := r.LookupHost(context.Background(), host); err == nil {
+			t.Fatalf("Wanted error, but got nil")
+		}
+	}
+
+	if len(r.breakers) != 2 || r.breakerLRU.Len() != 2 {
+		t.Errorf("Wanted 2 breakers, but got %d", len(r.breakers))
+	}
+
+	if _, ok := r.breakers["a.local"]; ok {
+		t.Errorf("Wanted least recently used breaker to be evicted")
+	}
+}
+
+type hostsFallback struct{}
+
+func (hostsFallback) Fallback(context.Context, string) (any, bool) {
+	return []string{"127.0.0.1"}, true
+}
+
+func TestResolver_Fallback(t *testing.T) {
+	fake := &fakeResolver{}
+	fake.down.Store(true)
+
+	r, err := NewResolver(fake, func(host string) string { return host }, func() *CircuitBreaker[string, any] {
+		cb := NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+		cb.SetFallback(hostsFallback{})
+
+		return cb
+	}, time.Hour, 16)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	_, _ = r.LookupHost(ctx, "api.local")
+
+	if _, err := r.LookupHost(ctx, "api.local"); !errors.Is(err, ErrResolverFallback) {
+		t.Errorf("Wanted %s, but got %v", ErrResolverFallback, err)
+	}
+}
+
+func TestNewResolver_Invalid(t *testing.T) {
+	for _, maxEntries := range []int{0, -1} {
+		_, err := NewResolver(&fakeResolver{}, func(host string) string { return host }, func() *CircuitBreaker[string, any] {
+			return NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+		}, time.Hour, maxEntries)
+		if !errors.Is(err, ErrResolverMaxEntries) {
+			t.Errorf("Wanted %s for max entries %d, but got %v", ErrResolverMaxEntries, maxEntries, err)
+		}
+	}
+}
+
+func TestResolver_LookupHost_Copy(t *testing.T) {
+	fake := &fakeResolver{hosts: map[string][]string{"api.local": {"10.0.0.1"}}}
+
+	r, err := NewResolver(fake, func(host string) string { return host }, func() *CircuitBreaker[string, any] {
+		return NewCB[string, any](time.Second, time.Minute, 50, 1, 1)
+	}, time.Hour, 16)
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	addrs, err := r.LookupHost(ctx, "api.local")
+	if err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	// изменение ответа вызывающим не должно менять кэш
+	addrs[0] = "changed"
+
+	fake.down.Store(true)
+	_, _ = r.LookupHost(ctx, "api.local")
+
+	for i := 0; i < 2; i++ {
+		addrs, err = r.LookupHost(ctx, "api.local")
+		if err != nil || !slices.Equal(addrs, []string{"10.0.0.1"}) {
+			t.Fatalf("Wanted stale 10.0.0.1, but got %v, %v", addrs, err)
+		}
+
+		addrs[0] = "changed"
+	}
+}
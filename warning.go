@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrWarningInvalid = errors.New("warning level must be in (0, 1] and hysteresis in [0, level)")
+)
+
+// Warning - событие о появлении или снятии предупреждения перед срабатыванием
+type Warning struct {
+	// Active - true - предупреждение появилось, false - снято
+	Active bool `json:"active"`
+	// FailureRate - процент ошибок в момент события
+	FailureRate float64 `json:"failure_rate"`
+	// Threshold - процент ошибок, при котором появляется предупреждение
+	Threshold float64   `json:"threshold"`
+	At        time.Time `json:"at"`
+}
+
+type warning struct {
+	// level - доля от errorThreshold, начиная с которой появляется предупреждение
+	level float64
+	// hysteresis - предупреждение снимается, только когда процент ошибок опустится ниже (level - hysteresis) * errorThreshold
+	hysteresis float64
+	active     bool
+	fn         func(Warning)
+	count      int64
+}
+
+// SetWarning - включает предупреждения: в статусе closed, когда процент ошибок достигает level * errorThreshold,
+// вызывается fn с Active = true. Предупреждение снимается, когда процент ошибок опускается ниже
+// (level - hysteresis) * errorThreshold или предохранитель срабатывает
+func (cb *CircuitBreaker[TRequest, TResponse]) SetWarning(level, hysteresis float64, fn func(Warning)) error {
+	if level <= 0 || level > 1 || hysteresis < 0 || hysteresis >= level {
+		return ErrWarningInvalid
+	}
+
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	cb.warning = &warning{
+		level:      level,
+		hysteresis: hysteresis,
+		fn:         fn,
+	}
+
+	return nil
+}
+
+// Warning - предохранитель в статусе closed, но процент ошибок близок к срабатыванию
+func (cb *CircuitBreaker[TRequest, TResponse]) Warning() bool {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	return cb.warning != nil && cb.warning.active && cb.status == StatusClosed
+}
+
+// Warnings - сколько раз появлялось предупреждение
+func (cb *CircuitBreaker[TRequest, TResponse]) Warnings() int64 {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+
+	if cb.warning == nil {
+		return 0
+	}
+
+	return cb.warning.count
+}
+
+func (cb *CircuitBreaker[TRequest, TResponse]) handleWarning(errorsPercentage float64) {
+	cb.mx.Lock()
+
+	w := cb.warning
+	if w == nil {
+		cb.mx.Unlock()
+		return
+	}
+
+	threshold := w.level * cb.errorThreshold
+	active := w.active
+
+	switch {
+	case cb.status != StatusClosed:
+		active = false
+	case errorsPercentage >= threshold:
+		active = true
+	case errorsPercentage < (w.level-w.hysteresis)*cb.errorThreshold:
+		active = false
+	}
+
+	changed := active != w.active
+	w.active = active
+	if changed && active {
+		w.count++
+	}
+	fn := w.fn
+
+	cb.mx.Unlock()
+
+	if changed && fn != nil {
+		fn(Warning{
+			Active:      active,
+			FailureRate: errorsPercentage,
+			Threshold:   threshold,
+			At:          time.Now(),
+		})
+	}
+}
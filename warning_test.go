@@ -0,0 +1,66 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_SetWarning(t *testing.T) {
+	cb := NewCB[time.Duration, string](time.Second, time.Minute, 50, 1, 10)
+
+	var events []bool
+
+	if err := cb.SetWarning(0.6, 0.2, func(w Warning) {
+		events = append(events, w.Active)
+	}); err != nil {
+		t.Fatalf("Got error: %s", err.Error())
+	}
+
+	if err := cb.SetWarning(0.6, 0.6, nil); err == nil {
+		t.Errorf("Wanted error, but got nil")
+	}
+
+	responses := func(success bool, n int) {
+		for i := 0; i < n; i++ {
+			cb.handleResponse(success)
+		}
+	}
+
+	// 30% ошибок - предупреждение
+	responses(true, 7)
+	responses(false, 3)
+
+	if !cb.Warning() || cb.Status() != StatusClosed {
+		t.Errorf("Wanted closed with warning, but got status=%s warning=%v", cb.Status(), cb.Warning())
+	}
+
+	// 20% ошибок - предупреждение держится из-за гистерезиса
+	responses(true, 8)
+
+	if !cb.Warning() {
+		t.Errorf("Wanted warning to hold at 20%%")
+	}
+
+	// 10% ошибок - предупреждение снимается
+	responses(true, 1)
+
+	if cb.Warning() {
+		t.Errorf("Wanted warning to clear at 10%%")
+	}
+
+	// рост ошибок до срабатывания: предупреждение появляется, а после срабатывания снимается
+	responses(false, 5)
+
+	if cb.Status() != StatusOpen || cb.Warning() {
+		t.Errorf("Wanted open without warning, but got status=%s warning=%v", cb.Status(), cb.Warning())
+	}
+
+	if want := []bool{true, false, true, false}; !slices.Equal(events, want) {
+		t.Errorf("Wanted events %v, but got %v", want, events)
+	}
+
+	if cb.Warnings() != 2 {
+		t.Errorf("Wanted 2 warnings, but got %d", cb.Warnings())
+	}
+}